@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
+	"github.com/edfun317/go-gcp/shell/podshell"
 	"github.com/spf13/cobra"
 )
 
@@ -24,17 +29,82 @@ var gcpCommands = []string{
 	//	"gsutil - Access Google Cloud Storage",
 }
 
+// newAccessPods creates a session with the global --timeout, --retries and --verbose applied
+func newAccessPods(cmd *cobra.Command, filePath string) *podshell.AccessPods {
+	access := podshell.NewAccessPods(filePath)
+	access.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	access.Retries, _ = cmd.Flags().GetInt("retries")
+	access.Verbose, _ = cmd.Flags().GetBool("verbose")
+	return access
+}
+
+// interruptContext returns a context cancelled on Ctrl+C for non-interactive commands
+func interruptContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt)
+}
+
+// exitWithError prints err with its suggested fix to stderr and exits with
+// the code of its kind
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := podshell.Hint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+	os.Exit(podshell.ExitCode(err))
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately
 func Execute() {
+	// Add global flags
+	rootCmd.PersistentFlags().StringP("output", "o", "table",
+		fmt.Sprintf("Output format for non-interactive mode (%s)", strings.Join(podshell.OutputFormats, "|")))
+	rootCmd.PersistentFlags().Duration("timeout", 60*time.Second,
+		"Limit for a single gcloud, kubectl or API call (0 disables); overridden per environment by timeout in the configuration")
+	rootCmd.PersistentFlags().Int("retries", 3,
+		"Retries of transient API and gcloud failures for reads and credentials (0 disables); overridden per environment by retries")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Report retries on stderr")
+
 	// Add flags to shell command
-	shellCmd.Flags().StringP("file", "f", "", "File path to use")
+	shellCmd.Flags().StringP("file", "f", "", "Configuration file (default: discovered, see config path)")
 	shellCmd.Flags().BoolP("list", "l", false, "List available GCP commands")
+	shellCmd.Flags().StringP("env", "e", "", "Environment to connect to instead of prompting for one")
+	shellCmd.Flags().StringP("run", "r", "", fmt.Sprintf("Run a single action non-interactively (%s)", strings.Join(podshell.RunActions(), ", ")))
+	shellCmd.Flags().String("pod", "", "Target pod for non-interactive actions")
+	shellCmd.Flags().String("container", "", "Target container for non-interactive actions")
+	shellCmd.Flags().String("filter", "", "Substring filter for the env action")
+	shellCmd.Flags().Bool("reveal-secrets", false, "Show secret-derived values in the env action")
+
+	// Add flags to query command
+	queryCmd.Flags().StringP("file", "f", "", "Configuration file (default: discovered, see config path)")
+	queryCmd.Flags().StringSlice("envs", nil, "Environments to query (default all)")
+	queryCmd.Flags().StringSlice("var", nil, "Environment variable names for the env query")
+	queryCmd.Flags().Bool("reveal-secrets", false, "Show secret-derived values in the env query")
+
+	// Add flags to diff command
+	diffCmd.Flags().StringP("file", "f", "", "Configuration file (default: discovered, see config path)")
+	diffCmd.Flags().StringP("namespace", "n", "", "Namespace to compare in both environments (default each environment's namespace)")
+	diffCmd.Flags().StringSlice("kinds", nil, fmt.Sprintf("Kinds to compare (%s)", strings.Join(podshell.DriftKinds(), ", ")))
 
-	// Mark file flag as required
-	shellCmd.MarkFlagRequired("file")
+	// Add flags to config commands
+	configValidateCmd.Flags().StringP("file", "f", "", "Configuration file (default: discovered, see config path)")
+	configPathCmd.Flags().StringP("file", "f", "", "Configuration file (default: discovered, see config path)")
+	configInitCmd.Flags().StringP("file", "f", "", "File to write (default $XDG_CONFIG_HOME/go-gcp/config)")
+	configInitCmd.Flags().StringSlice("projects", nil, "Projects to scan (default all visible projects)")
+	configInitCmd.Flags().Bool("force", false, "Overwrite an existing file without asking")
+	configImportCmd.Flags().StringP("file", "f", "", "Structured configuration file to merge into (default print to stdout)")
+	configImportCmd.Flags().String("kubeconfig", "", "Kubeconfig to read (default $KUBECONFIG or ~/.kube/config)")
+	configImportCmd.Flags().StringSlice("contexts", nil, "Contexts to import (default all)")
+	configImportCmd.Flags().Bool("use-context", false, "Connect through the kube context instead of fetching GKE credentials")
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configImportCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPathCmd)
 
 	// Add shell command to root command
 	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(configCmd)
 
 	// Execute root command
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/edfun317/go-gcp/shell/podshell"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the cluster configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Report every problem in the configuration files",
+	Long: `Check each configuration file in use and report all problems with their
+line numbers. Exits with a non-zero status when any problem is found`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath, _ := cmd.Flags().GetString("file")
+
+		checked, total := 0, 0
+		for _, source := range podshell.DiscoverConfig(filePath) {
+			if !source.Found {
+				continue
+			}
+			checked++
+			problems, err := podshell.ValidateConfig(source.Path)
+			if err != nil {
+				exitWithError(err)
+			}
+			if len(problems) == 0 {
+				fmt.Printf("%s: OK\n", source.Path)
+			}
+			for _, p := range problems {
+				fmt.Printf("%s:%d: %s\n", source.Path, p.Line, p.Message)
+			}
+			total += len(problems)
+		}
+
+		if checked == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no configuration file found, see 'config path'")
+			os.Exit(1)
+		}
+		if total > 0 {
+			fmt.Fprintf(os.Stderr, "%d problem(s) found\n", total)
+			os.Exit(podshell.KindInvalidInput.ExitCode())
+		}
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show which configuration files are loaded",
+	Long: `List the configuration files considered, lowest precedence first.
+Environments in later files override those with the same name in earlier ones`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath, _ := cmd.Flags().GetString("file")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LAYER\tPATH\tSTATUS")
+		for _, source := range podshell.DiscoverConfig(filePath) {
+			status := "not found"
+			if source.Found {
+				status = "loaded"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", source.Layer, source.Path, status)
+		}
+		w.Flush()
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a configuration file from your GKE clusters",
+	Long: `Enumerate the projects and GKE clusters visible to the active gcloud
+account, pick clusters, environment names and default namespaces, and write
+a structured configuration file`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := podshell.InitOptions{}
+		opts.Path, _ = cmd.Flags().GetString("file")
+		opts.Projects, _ = cmd.Flags().GetStringSlice("projects")
+		opts.Force, _ = cmd.Flags().GetBool("force")
+
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		if err := newAccessPods(cmd, opts.Path).InitConfig(ctx, opts); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import-kubeconfig",
+	Short: "Create environments from kubeconfig contexts",
+	Long: `Read the contexts of a kubeconfig and turn them into environments.
+GKE contexts (gke_<project>_<location>_<cluster>) keep their project, location
+and cluster; other contexts are used directly. Prints YAML unless --file is set`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := podshell.ImportOptions{}
+		opts.Kubeconfig, _ = cmd.Flags().GetString("kubeconfig")
+		opts.Path, _ = cmd.Flags().GetString("file")
+		opts.Contexts, _ = cmd.Flags().GetStringSlice("contexts")
+		opts.UseContext, _ = cmd.Flags().GetBool("use-context")
+
+		if err := podshell.ImportKubeconfig(opts); err != nil {
+			exitWithError(err)
+		}
+	},
+}
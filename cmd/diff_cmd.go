@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/edfun317/go-gcp/shell/podshell"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <left-env> <right-env>",
+	Short: "Detect configuration drift between two environments",
+	Long: `Compare Deployments, ConfigMaps and Services of the namespace in two
+environments, ignoring server-populated fields, and report differences in
+images, env, resources, replicas and labels`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath, _ := cmd.Flags().GetString("file")
+
+		// Check if an explicitly given file exists
+		if _, err := os.Stat(filePath); filePath != "" && os.IsNotExist(err) {
+			fmt.Printf("Error: file '%s' does not exist\n", filePath)
+			return
+		}
+
+		opts := podshell.DiffOptions{Left: args[0], Right: args[1]}
+		opts.Namespace, _ = cmd.Flags().GetString("namespace")
+		opts.Kinds, _ = cmd.Flags().GetStringSlice("kinds")
+		opts.Output, _ = cmd.Flags().GetString("output")
+
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		access := newAccessPods(cmd, filePath)
+		if err := access.Diff(ctx, opts); err != nil {
+			exitWithError(err)
+		}
+	},
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/edfun317/go-gcp/shell/podshell"
+	"github.com/spf13/cobra"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query <images|pods|replicas|env> <workload>",
+	Short: "Compare a workload across all configured environments",
+	Long: `Connect to every environment in the configuration file concurrently,
+run a read-only query against a workload and print the results side by side`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath, _ := cmd.Flags().GetString("file")
+
+		// Check if an explicitly given file exists
+		if _, err := os.Stat(filePath); filePath != "" && os.IsNotExist(err) {
+			fmt.Printf("Error: file '%s' does not exist\n", filePath)
+			return
+		}
+
+		opts := podshell.FanOutOptions{Query: args[0], Workload: args[1]}
+		opts.Envs, _ = cmd.Flags().GetStringSlice("envs")
+		opts.Vars, _ = cmd.Flags().GetStringSlice("var")
+		opts.Reveal, _ = cmd.Flags().GetBool("reveal-secrets")
+		opts.Output, _ = cmd.Flags().GetString("output")
+
+		ctx, stop := interruptContext(cmd)
+		defer stop()
+		access := newAccessPods(cmd, filePath)
+		if err := access.FanOut(ctx, opts); err != nil {
+			exitWithError(err)
+		}
+	},
+}
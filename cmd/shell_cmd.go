@@ -24,21 +24,44 @@ var shellCmd = &cobra.Command{
 
 		filePath, _ := cmd.Flags().GetString("file")
 
-		// Check if file exists
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		// Check if an explicitly given file exists
+		if _, err := os.Stat(filePath); filePath != "" && os.IsNotExist(err) {
 			fmt.Printf("Error: file '%s' does not exist\n", filePath)
 			return
 		}
 
-		// Process the file
-		fmt.Printf("loaded file: %s\n", filePath)
+		access := newAccessPods(cmd, filePath)
+		access.Env, _ = cmd.Flags().GetString("env")
+
+		// Run a single action without prompts when requested
+		if action, _ := cmd.Flags().GetString("run"); action != "" {
+			opts := podshell.RunOptions{Action: action, Env: access.Env}
+			opts.Pod, _ = cmd.Flags().GetString("pod")
+			opts.Container, _ = cmd.Flags().GetString("container")
+			opts.Filter, _ = cmd.Flags().GetString("filter")
+			opts.Reveal, _ = cmd.Flags().GetBool("reveal-secrets")
+			opts.Output, _ = cmd.Flags().GetString("output")
+			ctx, stop := interruptContext(cmd)
+			defer stop()
+			if err := access.Run(ctx, opts); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		// Report the configuration files in use
+		for _, source := range podshell.DiscoverConfig(filePath) {
+			if source.Found {
+				fmt.Printf("loaded file: %s\n", source.Path)
+			}
+		}
 
 		// Handle additional arguments
 		if len(args) > 0 {
 			fmt.Printf("Additional arguments: %v\n", args)
 		}
 
-		access := podshell.NewAccessPods(filePath)
-		access.Execute()
+		// The interactive shell handles Ctrl+C per action
+		access.Execute(cmd.Context())
 	},
 }
@@ -1,111 +1,249 @@
 package podshell
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // NewAccessPods creates and initializes a new AccessPods instance to manage pod operations.
 // It takes a filePath parameter specifying the location of cluster configuration file.
-// Returns a pointer to the initialized AccessPods struct with registered commands.
+// Returns a pointer to the initialized AccessPods struct with the built-in actions
+// and those added with Register.
 func NewAccessPods(filePath string) *AccessPods {
 	a := &AccessPods{
 		FilePath: filePath,
-		Commands: make(map[CommandType]ShellCommand),
+		Timeout:  defaultTimeout,
+		Retries:  defaultRetries,
+		Commands: NewRegistry(),
 	}
 	a.registerCommands()
+	// Register already rejected built-in names and duplicates, so a clash is a bug
+	for _, action := range defaultRegistry.Actions() {
+		if err := a.Commands.Register(action); err != nil {
+			panic(err)
+		}
+	}
 	return a
 }
 
-// registerCommands initializes all available pod management commands.
-// This includes commands for listing, connecting, viewing logs, describing pods,
-// showing environment variables, and program exit functionality.
+// registerCommands registers the built-in pod management actions.
+// This includes actions for listing, connecting, viewing logs, describing pods,
+// showing environment variables, resource tuning, scaling and port forwarding.
 func (a *AccessPods) registerCommands() {
-	// Define command order and properties
-	commandOrder := []struct {
-		cmdType     CommandType
-		description string
-		action      func(namespace string) error
-	}{
+	// LimitRanges and ResourceQuotas are checked when readable, so are not required
+	mutatesPods := []Permission{permListPods, permPatchPods}
+	// Hibernation annotates workloads and HPAs and scales through kubectl scale
+	patchWorkloads := []Permission{
+		permListDeploys, permListStateful, permListHPAs, permUpdateHPAs,
+		{Verb: "patch", Group: "apps", Resource: "deployments"},
+		{Verb: "patch", Group: "apps", Resource: "statefulsets"},
+		{Verb: "patch", Group: "apps", Resource: "deployments", Subresource: "scale"},
+		{Verb: "patch", Group: "apps", Resource: "statefulsets", Subresource: "scale"},
+		{Verb: "patch", Group: "autoscaling", Resource: "horizontalpodautoscalers"},
+	}
+
+	actions := []Action{
+		{
+			Name:        "list-pods",
+			Description: "List all pods",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods},
+			Handler:     namespaced(a.listPods),
+			run:         runListPods,
+		},
 		{
-			cmdType:     ShowPods,
-			description: "List all pods",
-			action:      a.listPods,
+			Name:        "connect",
+			Description: "Connect to a pod",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, {Verb: "create", Resource: "pods", Subresource: "exec"}},
+			Handler:     namespaced(a.connectToPodShell),
 		},
 		{
-			cmdType:     ConnectPod,
-			description: "Connect to a pod",
-			action:      a.connectToPodShell,
+			Name:        "logs",
+			Description: "Show pod logs",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, {Verb: "get", Resource: "pods", Subresource: "log"}},
+			Handler:     namespaced(a.showPodLogs),
 		},
 		{
-			cmdType:     ShowLogs,
-			description: "Show pod logs",
-			action:      a.showPodLogs,
+			Name:        "describe",
+			Description: "Describe pod",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, permGetPods, permListEvents},
+			Handler:     namespaced(a.describePod),
+			run:         runDescribePod,
 		},
 		{
-			cmdType:     DescribePod,
-			description: "Describe pod",
-			action:      a.describePod,
+			Name:        "diagnose",
+			Description: "Diagnose pod failures",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, permListEvents},
+			Handler:     namespaced(a.diagnosePod),
 		},
 		{
-			cmdType:     ShowEnv,
-			description: "Show environment variables",
-			action:      a.showPodEnv,
+			Name:        "env",
+			Description: "Show environment variables",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, permGetConfigMaps, permGetSecrets},
+			Handler:     namespaced(a.showPodEnv),
+			run:         runShowEnv,
 		},
 		{
-			cmdType:     AdjustCPU,
-			description: "Adjust pod CPU resources",
-			action:      a.adjustPodCPU,
+			Name:        "diff-env",
+			Description: "Compare environment variables",
+			Category:    "Pods",
+			Permissions: []Permission{permListPods, permGetConfigMaps, permGetSecrets},
+			Handler:     namespaced(a.diffPodEnv),
 		},
 		{
-			cmdType:     AdjustMemory,
-			description: "Adjust pod memory resources",
-			action:      a.adjustPodMemory,
+			Name:        "overview",
+			Description: "Namespace overview",
+			Category:    "Namespace",
+			Permissions: []Permission{
+				permListDeploys, permListStateful,
+				{Verb: "list", Group: "apps", Resource: "daemonsets"},
+				permListPods,
+				{Verb: "list", Resource: "services"},
+				{Verb: "list", Resource: "endpoints"},
+				{Verb: "list", Group: "networking.k8s.io", Resource: "ingresses"},
+				{Verb: "list", Resource: "persistentvolumeclaims"},
+				permListEvents,
+			},
+			Handler: namespaced(a.namespaceOverviewAction),
 		},
 		{
-			cmdType:     ScaleDeployment,
-			description: "Scale deployment replicas",
-			action:      a.scaleDeployment,
+			Name:             "hibernate",
+			Description:      "Hibernate namespace (scale all workloads to zero)",
+			Category:         "Namespace",
+			Mutating:         true,
+			RefusesProtected: true,
+			Permissions:      patchWorkloads,
+			Handler:          namespaced(a.hibernateNamespace),
 		},
 		{
-			cmdType:     PortForward,
-			description: "Port forward service to localhost",
-			action:      a.portForward,
+			Name:        "wake",
+			Description: "Wake hibernated namespace",
+			Category:    "Namespace",
+			Mutating:    true,
+			Permissions: patchWorkloads,
+			Handler:     namespaced(a.wakeNamespace),
 		},
 		{
-			cmdType:     Exit,
-			description: "Exit program",
-			action:      func(namespace string) error { os.Exit(0); return nil },
+			Name:        "resources",
+			Description: "Show container requests and limits",
+			Category:    "Resources",
+			Permissions: []Permission{permListPods},
+			Handler:     namespaced(a.showResult(runShowResources)),
+			run:         runShowResources,
+		},
+		{
+			Name:        "top",
+			Description: "Show live resource usage",
+			Category:    "Resources",
+			Permissions: []Permission{permListPods, permListPodUsage},
+			Handler:     namespaced(a.topPods),
+		},
+		{
+			Name:        "right-size",
+			Description: "Recommend resource requests/limits",
+			Category:    "Resources",
+			Mutating:    true,
+			Permissions: append([]Permission{{Verb: "get", Group: "metrics.k8s.io", Resource: "pods"}}, mutatesPods...),
+			Handler:     namespaced(a.rightSizePod),
+		},
+		{
+			Name:        "adjust-cpu",
+			Description: "Adjust pod CPU resources",
+			Category:    "Resources",
+			Mutating:    true,
+			Permissions: mutatesPods,
+			Handler:     namespaced(a.adjustPodCPU),
+		},
+		{
+			Name:        "adjust-memory",
+			Description: "Adjust pod memory resources",
+			Category:    "Resources",
+			Mutating:    true,
+			Permissions: mutatesPods,
+			Handler:     namespaced(a.adjustPodMemory),
+		},
+		{
+			Name:        "adjust-storage",
+			Description: "Adjust pod ephemeral-storage resources",
+			Category:    "Resources",
+			Mutating:    true,
+			Permissions: mutatesPods,
+			Handler:     namespaced(a.adjustPodStorage),
+		},
+		{
+			Name:        "replicas",
+			Description: "Show workload replicas and autoscalers",
+			Category:    "Workloads",
+			Permissions: []Permission{permListDeploys, permListStateful, permListHPAs},
+			Handler:     namespaced(a.showResult(runShowReplicas)),
+			run:         runShowReplicas,
+		},
+		{
+			Name:        "scale",
+			Description: "Scale deployment replicas",
+			Category:    "Workloads",
+			Mutating:    true,
+			Permissions: []Permission{
+				permListDeploys, permListHPAs,
+				{Verb: "patch", Group: "apps", Resource: "deployments", Subresource: "scale"},
+			},
+			Handler: namespaced(a.scaleDeployment),
+		},
+		{
+			Name:        "hpa",
+			Description: "Inspect and edit horizontal pod autoscalers",
+			Category:    "Workloads",
+			Mutating:    true,
+			Permissions: []Permission{permListHPAs, permUpdateHPAs},
+			Handler:     namespaced(a.manageHPA),
+		},
+		{
+			Name:        "port-forward",
+			Description: "Port forward service to localhost",
+			Category:    "Network",
+			Permissions: []Permission{
+				{Verb: "list", Resource: "services"},
+				{Verb: "get", Resource: "services"},
+				{Verb: "create", Resource: "pods", Subresource: "portforward"},
+			},
+			Handler: namespaced(a.portForward),
 		},
 	}
 
-	// Initialize command map
-	a.Commands = make(map[CommandType]ShellCommand)
-
-	// Register each command with its properties
-	for _, cmd := range commandOrder {
-		a.Commands[cmd.cmdType] = ShellCommand{
-			Type:        cmd.cmdType,
-			Description: cmd.description,
-			Action:      cmd.action,
+	// A rejected built-in action is a programming error
+	for _, action := range actions {
+		if err := a.Commands.Register(action); err != nil {
+			panic(err)
 		}
 	}
 }
 
+// namespaced adapts a built-in action taking the namespace to an Action handler
+func namespaced(action func(ctx context.Context, namespace string) error) func(context.Context, *Session) error {
+	return func(ctx context.Context, s *Session) error {
+		return action(ctx, s.Namespace)
+	}
+}
+
 // listPods executes kubectl command to list all pods in the specified namespace.
 // Displays pod information directly to stdout.
-func (a *AccessPods) listPods(namespace string) error {
-	cmd := exec.Command("kubectl", "get", "pods", "-n", namespace)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func (a *AccessPods) listPods(ctx context.Context, namespace string) error {
+	_, err := runKubectl(ctx, a.config, os.Stdout, "get", "pods", "-n", namespace)
+	return err
 }
 
 // connectToPodShell establishes an interactive shell connection to a selected pod.
 // First retrieves available pods, then lets user select one before connecting.
-func (a *AccessPods) connectToPodShell(namespace string) error {
-	pods, err := getPods(namespace)
+func (a *AccessPods) connectToPodShell(ctx context.Context, namespace string) error {
+	pods, err := getPods(ctx, a.config, namespace)
 	if err != nil {
 		return err
 	}
@@ -113,13 +251,13 @@ func (a *AccessPods) connectToPodShell(namespace string) error {
 	if err != nil {
 		return err
 	}
-	return connectToPod(selectedPod, namespace)
+	return connectToPod(ctx, a.config, selectedPod, namespace)
 }
 
 // showPodLogs retrieves and displays logs from a selected pod.
 // User can select which pod's logs to view from available pods.
-func (a *AccessPods) showPodLogs(namespace string) error {
-	pods, err := getPods(namespace)
+func (a *AccessPods) showPodLogs(ctx context.Context, namespace string) error {
+	pods, err := getPods(ctx, a.config, namespace)
 	if err != nil {
 		return err
 	}
@@ -127,16 +265,14 @@ func (a *AccessPods) showPodLogs(namespace string) error {
 	if err != nil {
 		return err
 	}
-	cmd := exec.Command("kubectl", "logs", selectedPod, "-n", namespace)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err = runKubectl(ctx, a.config, os.Stdout, "logs", selectedPod, "-n", namespace)
+	return err
 }
 
 // describePod shows detailed information about a selected pod.
 // Executes kubectl describe command on the chosen pod.
-func (a *AccessPods) describePod(namespace string) error {
-	pods, err := getPods(namespace)
+func (a *AccessPods) describePod(ctx context.Context, namespace string) error {
+	pods, err := getPods(ctx, a.config, namespace)
 	if err != nil {
 		return err
 	}
@@ -144,103 +280,29 @@ func (a *AccessPods) describePod(namespace string) error {
 	if err != nil {
 		return err
 	}
-	cmd := exec.Command("kubectl", "describe", "pod", selectedPod, "-n", namespace)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-// showPodEnv displays environment variables for a selected pod.
-// Executes 'env' command inside the pod to list all environment variables.
-func (a *AccessPods) showPodEnv(namespace string) error {
-	// Get and select target pod
-	pods, err := getPods(namespace)
-	if err != nil {
-		return err
-	}
-	selectedPod, err := selectPod(pods)
-	if err != nil {
-		return err
-	}
-
-	// Execute command to retrieve environment variables
-	cmd := exec.Command("kubectl", "exec", selectedPod, "-n", namespace, "--", "env")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err = runKubectl(ctx, a.config, os.Stdout, "describe", "pod", selectedPod, "-n", namespace)
+	return err
 }
 
 // adjustPodCPU modifies the CPU resource requests/limits for a selected pod.
-func (a *AccessPods) adjustPodCPU(namespace string) error {
-	pods, err := getPods(namespace)
-	if err != nil {
-		return err
-	}
-	selectedPod, err := selectPod(pods)
-	if err != nil {
-		return err
-	}
-
-	// Get current resource values
-	cmd := exec.Command("kubectl", "get", "pod", selectedPod, "-n", namespace, "-o", "jsonpath={.spec.containers[0].resources}")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-
-	// Prompt for new CPU value
-	fmt.Print("\nEnter new CPU value (e.g., '500m' for 500 millicores or '2' for 2 cores): ")
-	var cpuValue string
-	fmt.Scanln(&cpuValue)
-
-	// Apply the new CPU value
-	patchStr := fmt.Sprintf(`{"spec":{"containers":[{"name":"*","resources":{"requests":{"cpu":"%s"},"limits":{"cpu":"%s"}}}]}}`, cpuValue, cpuValue)
-	cmd = exec.Command("kubectl", "patch", "pod", selectedPod, "-n", namespace, "--type=strategic", "-p", patchStr)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func (a *AccessPods) adjustPodCPU(ctx context.Context, namespace string) error {
+	return a.adjustPodResource(ctx, namespace, corev1.ResourceCPU, "e.g., '500m' for 500 millicores or '2' for 2 cores")
 }
 
 // adjustPodMemory modifies the memory resource requests/limits for a selected pod.
-func (a *AccessPods) adjustPodMemory(namespace string) error {
-	pods, err := getPods(namespace)
-	if err != nil {
-		return err
-	}
-	selectedPod, err := selectPod(pods)
-	if err != nil {
-		return err
-	}
-
-	// Get current resource values
-	cmd := exec.Command("kubectl", "get", "pod", selectedPod, "-n", namespace, "-o", "jsonpath={.spec.containers[0].resources}")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-
-	// Prompt for new memory value
-	fmt.Print("\nEnter new memory value (e.g., '512Mi' or '2Gi'): ")
-	var memValue string
-	fmt.Scanln(&memValue)
+func (a *AccessPods) adjustPodMemory(ctx context.Context, namespace string) error {
+	return a.adjustPodResource(ctx, namespace, corev1.ResourceMemory, "e.g., '512Mi' or '2Gi'")
+}
 
-	// Apply the new memory value
-	patchStr := fmt.Sprintf(`{"spec":{"containers":[{"name":"*","resources":{"requests":{"memory":"%s"},"limits":{"memory":"%s"}}}]}}`, memValue, memValue)
-	cmd = exec.Command("kubectl", "patch", "pod", selectedPod, "-n", namespace, "--type=strategic", "-p", patchStr)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// adjustPodStorage modifies the ephemeral-storage resource requests/limits for a selected pod.
+func (a *AccessPods) adjustPodStorage(ctx context.Context, namespace string) error {
+	return a.adjustPodResource(ctx, namespace, corev1.ResourceEphemeralStorage, "e.g., '1Gi' or '10Gi'")
 }
 
 // scaleDeployment modifies the number of replicas for a deployment.
-func (a *AccessPods) scaleDeployment(namespace string) error {
+func (a *AccessPods) scaleDeployment(ctx context.Context, namespace string) error {
 	// Get list of deployments
-	cmd := exec.Command("kubectl", "get", "deployments", "-n", namespace)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if _, err := runKubectl(ctx, a.config, os.Stdout, "get", "deployments", "-n", namespace); err != nil {
 		return err
 	}
 
@@ -249,34 +311,46 @@ func (a *AccessPods) scaleDeployment(namespace string) error {
 	var deploymentName string
 	fmt.Scanln(&deploymentName)
 
+	// Manual scaling is overridden by an HPA targeting the deployment
+	hpa, err := findHPAForTarget(ctx, a.client, namespace, "Deployment", deploymentName)
+	if err != nil {
+		return err
+	}
+	if hpa != nil {
+		fmt.Printf("\n%sWarning: deployment %s is managed by HPA %s; manual scaling will be overridden%s\n",
+			colorYellow, deploymentName, hpa.Name, colorReset)
+		if a.getUserConfirmation("Manage the HPA instead? (y/n): ") {
+			return a.hpaActions(ctx, hpa)
+		}
+	}
+
 	// Get current replicas
-	cmd = exec.Command("kubectl", "get", "deployment", deploymentName, "-n", namespace, "-o", "jsonpath={.spec.replicas}")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 	fmt.Printf("\nCurrent replicas: ")
-	if err := cmd.Run(); err != nil {
+	if _, err := runKubectl(ctx, a.config, os.Stdout, "get", "deployment", deploymentName, "-n", namespace, "-o", "jsonpath={.spec.replicas}"); err != nil {
 		return err
 	}
 
 	// Get new replica count from user
-	fmt.Print("\nEnter new number of replicas: ")
-	var replicaCount string
-	fmt.Scanln(&replicaCount)
+	replicas, err := promptReplicas("Enter new number of replicas: ")
+	if err != nil {
+		return err
+	}
 
 	// Scale the deployment
-	cmd = exec.Command("kubectl", "scale", "deployment", deploymentName, "-n", namespace, fmt.Sprintf("--replicas=%s", replicaCount))
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return scaleWorkload(ctx, a.config, "deployment", deploymentName, namespace, replicas)
+}
+
+// scaleWorkload sets the replica count of a deployment or statefulset.
+// Command: kubectl scale deployment my-app -n namespace --replicas=3
+func scaleWorkload(ctx context.Context, config ClusterConfig, kind, name, namespace string, replicas int32) error {
+	_, err := runKubectl(ctx, config, os.Stdout, "scale", kind, name, "-n", namespace, fmt.Sprintf("--replicas=%d", replicas))
+	return err
 }
 
 // portForward forwards a local port to a service in the GKE cluster.
-func (a *AccessPods) portForward(namespace string) error {
+func (a *AccessPods) portForward(ctx context.Context, namespace string) error {
 	// Get list of services
-	cmd := exec.Command("kubectl", "get", "services", "-n", namespace)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if _, err := runKubectl(ctx, a.config, os.Stdout, "get", "services", "-n", namespace); err != nil {
 		return err
 	}
 
@@ -286,11 +360,8 @@ func (a *AccessPods) portForward(namespace string) error {
 	fmt.Scanln(&serviceName)
 
 	// Get target port from user
-	cmd = exec.Command("kubectl", "get", "service", serviceName, "-n", namespace, "-o", "jsonpath={.spec.ports[*].port}")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 	fmt.Printf("\nAvailable ports: ")
-	if err := cmd.Run(); err != nil {
+	if _, err := runKubectl(ctx, a.config, os.Stdout, "get", "service", serviceName, "-n", namespace, "-o", "jsonpath={.spec.ports[*].port}"); err != nil {
 		return err
 	}
 
@@ -303,9 +374,9 @@ func (a *AccessPods) portForward(namespace string) error {
 	var localPort string
 	fmt.Scanln(&localPort)
 
-	// Start port forwarding
-	fmt.Printf("\nStarting port forward from localhost:%s to service %s:%s\n", localPort, serviceName, targetPort)
-	cmd = exec.Command("kubectl", "port-forward", fmt.Sprintf("service/%s", serviceName), fmt.Sprintf("%s:%s", localPort, targetPort), "-n", namespace)
+	// Start port forwarding; it runs until interrupted
+	fmt.Printf("\nStarting port forward from localhost:%s to service %s:%s (Ctrl+C to stop)\n", localPort, serviceName, targetPort)
+	cmd := kubectlCommand(ctx, a.config, "port-forward", fmt.Sprintf("service/%s", serviceName), fmt.Sprintf("%s:%s", localPort, targetPort), "-n", namespace)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
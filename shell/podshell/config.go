@@ -0,0 +1,519 @@
+package podshell
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Syntax of GCP identifiers checked by ValidateConfig
+var (
+	// Project IDs: 6-30 chars, lowercase letters, digits and hyphens, starting
+	// with a letter, optionally scoped by a domain (example.com:my-project)
+	projectIDPattern = regexp.MustCompile(`^([a-z0-9.-]+:)?[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+	// Regions (us-central1) and zones (us-central1-a)
+	locationPattern = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+(-[a-z])?$`)
+)
+
+// ConfigProblem is a single issue found in a configuration file
+type ConfigProblem struct {
+	Line    int
+	Message string
+}
+
+func (p ConfigProblem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// configEntry is a parsed environment with the line it was defined on
+type configEntry struct {
+	config ClusterConfig
+	line   int
+}
+
+// readConfigurations reads and parses the cluster configuration file.
+// YAML files (see isStructuredConfig) use the structured format, anything else
+// the line format env|project|cluster|zone|namespace[|flags], e.g.
+// prod|my-project|my-cluster|us-central1-a|default|protected
+// Environments named prod, production or prd are always protected.
+// Structured files may also declare custom commands.
+func readConfigurations(filePath string) ([]ClusterConfig, []customCommand, error) {
+	entries, commands, problems, err := parseConfigFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration file %s: %s", filePath, problems[0])
+	}
+
+	configs := make([]ClusterConfig, 0, len(entries))
+	for _, entry := range entries {
+		configs = append(configs, entry.config)
+	}
+	// A layer may only add commands to environments defined elsewhere
+	if len(configs) == 0 && len(commands) == 0 {
+		return nil, nil, fmt.Errorf("no valid configurations found")
+	}
+	return configs, commands, nil
+}
+
+// ValidateConfig checks a configuration file and returns every problem found:
+// syntax errors, unknown keys, duplicate environment names, invalid project
+// IDs, zones or regions, namespaces that are not DNS labels, and invalid or
+// duplicate custom commands.
+func ValidateConfig(filePath string) ([]ConfigProblem, error) {
+	data, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return validateConfigData(filePath, data)
+}
+
+// validateConfigData is ValidateConfig for the contents of a file at filePath
+func validateConfigData(filePath string, data []byte) ([]ConfigProblem, error) {
+	entries, commands, problems, err := parseConfigData(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]int)
+	for _, entry := range entries {
+		c := entry.config
+		report := func(format string, args ...interface{}) {
+			problems = append(problems, ConfigProblem{entry.line, fmt.Sprintf(format, args...)})
+		}
+
+		if first, ok := seen[c.env]; ok {
+			report("duplicate environment %q (first defined on line %d)", c.env, first)
+		} else {
+			seen[c.env] = entry.line
+		}
+		// Context-based environments may leave the GKE fields empty
+		if !projectIDPattern.MatchString(c.project) && (c.project != "" || c.kubeContext == "") {
+			report("invalid GCP project ID %q", c.project)
+		}
+		if !locationPattern.MatchString(c.zone) && (c.zone != "" || c.kubeContext == "") {
+			report("invalid zone or region %q", c.zone)
+		}
+		for _, msg := range validation.IsDNS1123Label(c.namespace) {
+			report("invalid namespace %q: %s", c.namespace, msg)
+		}
+		if c.account != "" && !strings.Contains(c.account, "@") {
+			report("invalid account %q: expected an email address", c.account)
+		}
+		if c.impersonate != "" && !strings.HasSuffix(c.impersonate, ".gserviceaccount.com") {
+			report("invalid service account %q: expected <name>@<project>.iam.gserviceaccount.com", c.impersonate)
+		}
+		if c.credentialsFile != "" {
+			if _, err := os.Stat(c.credentialsFile); err != nil {
+				report("credentials file %s: %v", c.credentialsFile, err)
+			}
+		}
+		if c.proxy != "" {
+			if _, err := parseProxyURL(c.proxy); err != nil {
+				report("%v", err)
+			}
+		}
+		if c.tunnel != "" && c.proxy == "" {
+			report("tunnel requires a proxy URL pointing at the port it serves")
+		}
+	}
+	commandLines := make(map[string]int)
+	for _, c := range commands {
+		if first, ok := commandLines[c.name]; ok {
+			problems = append(problems, ConfigProblem{c.line, fmt.Sprintf("duplicate command %q (first defined on line %d)", c.name, first)})
+			continue
+		}
+		commandLines[c.name] = c.line
+		if isRegisteredAction(c.name) {
+			problems = append(problems, ConfigProblem{c.line, fmt.Sprintf("command %q clashes with a built-in action", c.name)})
+		}
+	}
+	if len(entries) == 0 && len(commands) == 0 && len(problems) == 0 {
+		problems = append(problems, ConfigProblem{0, "no environments defined"})
+	}
+
+	// Report in file order
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems, nil
+}
+
+// parseConfigFile parses a configuration file in either format, collecting
+// problems instead of stopping at the first one
+func parseConfigFile(filePath string) ([]configEntry, []customCommand, []ConfigProblem, error) {
+	data, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return parseConfigData(filePath, data)
+}
+
+// readConfigFile reads a configuration file
+func readConfigFile(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open configuration file: %v", err)
+	}
+	return data, nil
+}
+
+// parseConfigData parses the contents of a configuration file, whose format
+// depends on filePath as well
+func parseConfigData(filePath string, data []byte) ([]configEntry, []customCommand, []ConfigProblem, error) {
+	if isStructuredConfig(filePath, data) {
+		entries, commands, problems := parseStructuredConfig(data)
+		return entries, commands, problems, nil
+	}
+	entries, problems, err := parseLineConfig(bytes.NewReader(data))
+	return entries, nil, problems, err
+}
+
+// checkStructuredPath rejects paths that YAML cannot be written to because
+// they would be read back in the line format, such as clusters.conf
+func checkStructuredPath(filePath string) error {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml", "":
+		return nil
+	}
+	return &Error{
+		Kind: KindInvalidInput,
+		Err:  fmt.Errorf("%s would be read in the line format; structured configuration needs a .yaml or .yml name", filePath),
+		Hint: "use a file name ending in .yaml, or no extension",
+	}
+}
+
+// isStructuredConfig reports whether a file uses the YAML format: by its
+// .yaml/.yml extension, or for files without one (such as the user config)
+// when the first significant line is not a pipe-separated entry
+func isStructuredConfig(filePath string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return true
+	case "":
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			return !strings.Contains(line, "|")
+		}
+	}
+	return false
+}
+
+// parseLineConfig parses the pipe-separated line format
+func parseLineConfig(r io.Reader) ([]configEntry, []ConfigProblem, error) {
+	var entries []configEntry
+	var problems []ConfigProblem
+	scanner := bufio.NewScanner(r)
+
+	// Parse configuration file line by line
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		report := func(format string, args ...interface{}) {
+			problems = append(problems, ConfigProblem{lineNo, fmt.Sprintf(format, args...)})
+		}
+
+		// Split and validate configuration line
+		parts := strings.Split(line, "|")
+		if len(parts) != 5 && len(parts) != 6 {
+			report("expected 5 or 6 fields separated by '|', got %d", len(parts))
+			continue
+		}
+
+		// Create and validate config object
+		config := ClusterConfig{
+			env:       strings.TrimSpace(parts[0]),
+			project:   strings.TrimSpace(parts[1]),
+			cluster:   strings.TrimSpace(parts[2]),
+			zone:      strings.TrimSpace(parts[3]),
+			namespace: strings.TrimSpace(parts[4]),
+		}
+		valid := true
+		if len(parts) == 6 {
+			for _, flag := range strings.Split(parts[5], ",") {
+				key, value, hasValue := strings.Cut(strings.TrimSpace(flag), "=")
+				switch key {
+				case "protected":
+					// A bare protected flag means protected=true
+					config.protected = true
+					if hasValue {
+						protected, err := strconv.ParseBool(value)
+						if err != nil {
+							report("protected must be true or false, got %q", value)
+							valid = false
+						}
+						config.protected = protected
+					}
+				case "context":
+					config.kubeContext = value
+				case "account":
+					config.account = value
+				case "impersonate":
+					config.impersonate = value
+				case "credentials":
+					config.credentialsFile = value
+				case "proxy":
+					config.proxy = value
+				case "tunnel":
+					config.tunnel = value
+				case "timeout":
+					timeout, err := parseTimeout(value)
+					if err != nil {
+						report("%v", err)
+						valid = false
+					}
+					config.timeout = timeout
+				case "retries":
+					retries, err := parseRetries(value)
+					if err != nil {
+						report("%v", err)
+						valid = false
+					}
+					config.retries = &retries
+				case "":
+				default:
+					report("unknown flag %q", key)
+					valid = false
+				}
+			}
+		}
+
+		config.protected = config.protected || isProductionEnv(config.env)
+
+		// Verify all required fields are present
+		if missing := missingFields(config); len(missing) > 0 {
+			report("missing required fields: %s", strings.Join(missing, ", "))
+			valid = false
+		}
+		if valid {
+			entries = append(entries, configEntry{config, lineNo})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading configuration file: %v", err)
+	}
+	return entries, problems, nil
+}
+
+// parseStructuredConfig parses the YAML format:
+//
+//	environments:
+//	  - env: prod
+//	    project: my-project
+//	    cluster: my-cluster
+//	    zone: us-central1-a
+//	    namespace: default
+//	    protected: true
+//	    account: me@example.com # optional gcloud identity for this environment
+//	    impersonate: deployer@my-project.iam.gserviceaccount.com
+//	    credentials: /path/to/key.json
+//	    proxy: socks5://localhost:1080 # optional access path for private clusters
+//	    tunnel: gcloud compute ssh bastion --tunnel-through-iap --zone us-central1-a -- -N -D 1080
+//	    timeout: 2m # optional per-operation timeout, overrides --timeout
+//	    retries: 5 # optional retries of transient failures, overrides --retries
+//	  - env: local
+//	    context: kind-local # use a kubeconfig context instead of gcloud
+//	    namespace: default
+//	commands: # optional menu actions, see customCommand
+//	  - name: healthz
+//	    target: pod
+//	    exec: curl -s localhost:8080/healthz
+func parseStructuredConfig(data []byte) ([]configEntry, []customCommand, []ConfigProblem) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, []ConfigProblem{{yamlErrorLine(err), err.Error()}}
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, nil
+	}
+
+	var entries []configEntry
+	var commands []customCommand
+	var problems []ConfigProblem
+	report := func(node *yaml.Node, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{node.Line, fmt.Sprintf(format, args...)})
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		report(root, "expected a mapping with an environments list")
+		return nil, nil, problems
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "environments":
+			if value.Kind != yaml.SequenceNode {
+				report(value, "environments must be a list")
+				continue
+			}
+			for _, item := range value.Content {
+				if entry, ok := parseStructuredEnvironment(item, report); ok {
+					entries = append(entries, entry)
+				}
+			}
+		case "commands":
+			if value.Kind != yaml.SequenceNode {
+				report(value, "commands must be a list")
+				continue
+			}
+			for _, item := range value.Content {
+				if command, ok := parseCustomCommand(item, report); ok {
+					commands = append(commands, command)
+				}
+			}
+		default:
+			report(key, "unknown key %q", key.Value)
+		}
+	}
+	return entries, commands, problems
+}
+
+// parseStructuredEnvironment parses one item of the environments list
+func parseStructuredEnvironment(item *yaml.Node, report func(*yaml.Node, string, ...interface{})) (configEntry, bool) {
+	if item.Kind != yaml.MappingNode {
+		report(item, "environment must be a mapping")
+		return configEntry{}, false
+	}
+
+	var config ClusterConfig
+	valid := true
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		key, value := item.Content[i], item.Content[i+1]
+		var field *string
+		switch key.Value {
+		case "env":
+			field = &config.env
+		case "project":
+			field = &config.project
+		case "cluster":
+			field = &config.cluster
+		case "zone":
+			field = &config.zone
+		case "namespace":
+			field = &config.namespace
+		case "context":
+			field = &config.kubeContext
+		case "account":
+			field = &config.account
+		case "impersonate":
+			field = &config.impersonate
+		case "credentials":
+			field = &config.credentialsFile
+		case "proxy":
+			field = &config.proxy
+		case "tunnel":
+			field = &config.tunnel
+		case "protected":
+			if err := value.Decode(&config.protected); err != nil {
+				report(value, "protected must be true or false")
+				valid = false
+			}
+			continue
+		case "timeout":
+			timeout, err := parseTimeout(value.Value)
+			if err != nil || value.Kind != yaml.ScalarNode {
+				report(value, "timeout must be a duration such as 30s or 2m")
+				valid = false
+			}
+			config.timeout = timeout
+			continue
+		case "retries":
+			retries, err := parseRetries(value.Value)
+			if err != nil || value.Kind != yaml.ScalarNode {
+				report(value, "retries must be a number of at least 0")
+				valid = false
+			}
+			config.retries = &retries
+			continue
+		default:
+			report(key, "unknown key %q", key.Value)
+			valid = false
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			report(value, "%s must be a string", key.Value)
+			valid = false
+			continue
+		}
+		*field = strings.TrimSpace(value.Value)
+	}
+	config.protected = config.protected || isProductionEnv(config.env)
+
+	if missing := missingFields(config); len(missing) > 0 {
+		report(item, "missing required fields: %s", strings.Join(missing, ", "))
+		valid = false
+	}
+	return configEntry{config, item.Line}, valid
+}
+
+// parseTimeout parses a per-operation timeout such as 30s or 2m
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: expected a positive duration such as 30s or 2m", value)
+	}
+	return timeout, nil
+}
+
+// parseRetries parses the number of retries of transient failures
+func parseRetries(value string) (int, error) {
+	retries, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || retries < 0 {
+		return 0, fmt.Errorf("invalid retries %q: expected a number of at least 0", value)
+	}
+	return retries, nil
+}
+
+// missingFields lists the required fields that are empty. Environments
+// using a kubeconfig context only need a name and namespace.
+func missingFields(config ClusterConfig) []string {
+	required := []struct{ name, value string }{
+		{"env", config.env},
+		{"namespace", config.namespace},
+	}
+	if config.kubeContext == "" {
+		required = append(required, []struct{ name, value string }{
+			{"project", config.project},
+			{"cluster", config.cluster},
+			{"zone", config.zone},
+		}...)
+	}
+
+	var missing []string
+	for _, f := range required {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
+
+// yamlLinePattern extracts the line number from yaml.v3 syntax errors
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine returns the line a YAML syntax error refers to, or 0
+func yamlErrorLine(err error) int {
+	var line int
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		fmt.Sscan(m[1], &line)
+	}
+	return line
+}
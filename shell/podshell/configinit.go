@@ -0,0 +1,464 @@
+package podshell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DiscoveredCluster is a GKE cluster found by a ClusterDiscovery
+type DiscoveredCluster struct {
+	Project  string
+	Name     string
+	Location string // Zone or region
+	Status   string
+}
+
+// ClusterDiscovery enumerates the projects, clusters and namespaces a user
+// can access. GcloudDiscovery talks to GCP; StaticDiscovery serves fixed data.
+type ClusterDiscovery interface {
+	Projects(ctx context.Context) ([]string, error)
+	Clusters(ctx context.Context, project string) ([]DiscoveredCluster, error)
+	Namespaces(ctx context.Context, cluster DiscoveredCluster) ([]string, error)
+}
+
+// GcloudDiscovery discovers resources with the gcloud CLI and the Kubernetes API
+type GcloudDiscovery struct{}
+
+// Projects lists the active projects visible to the current gcloud account
+func (GcloudDiscovery) Projects(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "gcloud", "projects", "list",
+		"--filter=lifecycleState:ACTIVE", "--format=value(projectId)").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %v", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Clusters lists the GKE clusters of a project
+func (GcloudDiscovery) Clusters(ctx context.Context, project string) ([]DiscoveredCluster, error) {
+	out, err := exec.CommandContext(ctx, "gcloud", "container", "clusters", "list",
+		"--project", project, "--format=json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters in %s: %v", project, err)
+	}
+	var items []struct {
+		Name     string `json:"name"`
+		Location string `json:"location"`
+		Status   string `json:"status"`
+	}
+	if err := json.Unmarshal(out, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse clusters of %s: %v", project, err)
+	}
+	clusters := make([]DiscoveredCluster, 0, len(items))
+	for _, item := range items {
+		clusters = append(clusters, DiscoveredCluster{project, item.Name, item.Location, item.Status})
+	}
+	return clusters, nil
+}
+
+// Namespaces fetches credentials for the cluster and lists its namespaces
+func (GcloudDiscovery) Namespaces(ctx context.Context, cluster DiscoveredCluster) ([]string, error) {
+	client, cleanup, err := connectToEnvironment(ctx, ClusterConfig{
+		env:     cluster.Name,
+		project: cluster.Project,
+		cluster: cluster.Name,
+		zone:    cluster.Location,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+// StaticDiscovery is an in-memory ClusterDiscovery for tests and offline use
+type StaticDiscovery struct {
+	ClusterList         []DiscoveredCluster
+	NamespacesByCluster map[string][]string // Keyed by project/cluster
+}
+
+// Projects returns the projects of the configured clusters
+func (s StaticDiscovery) Projects(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var projects []string
+	for _, c := range s.ClusterList {
+		if !seen[c.Project] {
+			seen[c.Project] = true
+			projects = append(projects, c.Project)
+		}
+	}
+	return projects, nil
+}
+
+// Clusters returns the configured clusters of a project
+func (s StaticDiscovery) Clusters(ctx context.Context, project string) ([]DiscoveredCluster, error) {
+	var clusters []DiscoveredCluster
+	for _, c := range s.ClusterList {
+		if c.Project == project {
+			clusters = append(clusters, c)
+		}
+	}
+	return clusters, nil
+}
+
+// Namespaces returns the configured namespaces of a cluster
+func (s StaticDiscovery) Namespaces(ctx context.Context, cluster DiscoveredCluster) ([]string, error) {
+	return s.NamespacesByCluster[cluster.Project+"/"+cluster.Name], nil
+}
+
+// timeoutDiscovery bounds each call of another ClusterDiscovery
+type timeoutDiscovery struct {
+	ClusterDiscovery
+	timeout time.Duration
+}
+
+func (d timeoutDiscovery) Projects(ctx context.Context) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+	return d.ClusterDiscovery.Projects(ctx)
+}
+
+func (d timeoutDiscovery) Clusters(ctx context.Context, project string) ([]DiscoveredCluster, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+	return d.ClusterDiscovery.Clusters(ctx, project)
+}
+
+func (d timeoutDiscovery) Namespaces(ctx context.Context, cluster DiscoveredCluster) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+	return d.ClusterDiscovery.Namespaces(ctx, cluster)
+}
+
+// InitOptions configures config init
+type InitOptions struct {
+	Path      string           // File to write; empty uses the user configuration path
+	Projects  []string         // Projects to scan; empty scans every visible project
+	Force     bool             // Overwrite an existing file without asking
+	Discovery ClusterDiscovery // Defaults to GcloudDiscovery
+}
+
+// InitConfig enumerates clusters, lets the user pick clusters, environment
+// names and default namespaces, and writes a structured configuration file.
+func (a *AccessPods) InitConfig(ctx context.Context, opts InitOptions) error {
+	discovery := opts.Discovery
+	if discovery == nil {
+		discovery = GcloudDiscovery{}
+	}
+	discovery = timeoutDiscovery{discovery, a.Timeout}
+	path := opts.Path
+	if path == "" {
+		path = filepath.Join(xdgConfigHome(), "go-gcp", "config")
+	}
+	if err := checkStructuredPath(path); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !opts.Force {
+		if !a.getUserConfirmation(fmt.Sprintf("%s already exists. Overwrite? (y/n): ", path)) {
+			return fmt.Errorf("init cancelled")
+		}
+	}
+
+	// Enumerate clusters
+	projects := opts.Projects
+	if len(projects) == 0 {
+		var err error
+		fmt.Println("Listing projects...")
+		if projects, err = discovery.Projects(ctx); err != nil {
+			return err
+		}
+	}
+	var clusters []DiscoveredCluster
+	for _, project := range projects {
+		found, err := discovery.Clusters(ctx, project)
+		if err != nil {
+			// Projects without the GKE API enabled are common; skip them
+			fmt.Printf("%sSkipping %s: %v%s\n", colorYellow, project, err, colorReset)
+			continue
+		}
+		clusters = append(clusters, found...)
+	}
+	if len(clusters) == 0 {
+		return fmt.Errorf("no GKE clusters found")
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Project != clusters[j].Project {
+			return clusters[i].Project < clusters[j].Project
+		}
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	fmt.Printf("\n%sAvailable clusters:%s\n", colorYellow, colorReset)
+	for i, c := range clusters {
+		fmt.Printf("%d. %s/%s (%s) %s\n", i+1, c.Project, c.Name, c.Location, c.Status)
+	}
+	selected, err := promptSelection(fmt.Sprintf("Select clusters (e.g. 1,3 or all) [1-%d]: ", len(clusters)), len(clusters))
+	if err != nil {
+		return err
+	}
+
+	// Name each environment and pick its namespace
+	var configs []ClusterConfig
+	for _, i := range selected {
+		c := clusters[i]
+		fmt.Printf("\n%s%s/%s%s\n", colorYellow, c.Project, c.Name, colorReset)
+
+		env := promptString(fmt.Sprintf("Environment name [%s]: ", c.Name), c.Name)
+		namespace, err := a.promptNamespace(ctx, discovery, c)
+		if err != nil {
+			return err
+		}
+		configs = append(configs, ClusterConfig{
+			env:       env,
+			project:   c.Project,
+			cluster:   c.Name,
+			zone:      c.Location,
+			namespace: namespace,
+			protected: isProductionEnv(env),
+		})
+	}
+
+	// Refuse anything the user typed that would not pass validation, before
+	// an existing file is replaced
+	var data bytes.Buffer
+	if err := writeStructuredConfigTo(&data, configs, nil); err != nil {
+		return err
+	}
+	problems, err := validateConfigData(path, data.Bytes())
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("%s%s:%d: %s%s\n", colorYellow, path, p.Line, p.Message, colorReset)
+		}
+		return invalidInput("%d problem(s) found; %s was not written", len(problems), path)
+	}
+
+	if err := writeConfigFile(path, data.Bytes()); err != nil {
+		return err
+	}
+	fmt.Printf("%sWrote %d environment(s) to %s%s\n", colorGreen, len(configs), path, colorReset)
+	return nil
+}
+
+// promptNamespace lets the user pick one of the cluster's namespaces, falling
+// back to free text when they cannot be listed
+func (a *AccessPods) promptNamespace(ctx context.Context, discovery ClusterDiscovery, cluster DiscoveredCluster) (string, error) {
+	namespaces, err := discovery.Namespaces(ctx, cluster)
+	if err != nil || len(namespaces) == 0 {
+		if err != nil {
+			fmt.Printf("%sCould not list namespaces: %v%s\n", colorYellow, err, colorReset)
+		}
+		return promptString("Default namespace [default]: ", "default"), nil
+	}
+
+	for i, ns := range namespaces {
+		fmt.Printf("%d. %s\n", i+1, ns)
+	}
+	choice := a.getUserInput(fmt.Sprintf("Select default namespace (1-%d): ", len(namespaces)))
+	if choice < 1 || choice > len(namespaces) {
+		return "", invalidInput("invalid namespace selection")
+	}
+	return namespaces[choice-1], nil
+}
+
+// promptString reads a single word, returning def when the input is empty
+func promptString(prompt, def string) string {
+	var input string
+	fmt.Print(prompt)
+	fmt.Scanln(&input)
+	if input = strings.TrimSpace(input); input == "" {
+		return def
+	}
+	return input
+}
+
+// promptSelection reads a comma-separated list of 1-based indexes or "all"
+// and returns the 0-based indexes
+func promptSelection(prompt string, n int) ([]int, error) {
+	var input string
+	fmt.Print(prompt)
+	fmt.Scanln(&input)
+	if strings.EqualFold(strings.TrimSpace(input), "all") {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	seen := map[int]bool{}
+	for _, field := range strings.Split(input, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || i < 1 || i > n {
+			return nil, invalidInput("invalid selection %q", field)
+		}
+		if !seen[i] {
+			seen[i] = true
+			indexes = append(indexes, i-1)
+		}
+	}
+	return indexes, nil
+}
+
+// structuredEnvironment is the YAML schema of one environment, see parseStructuredConfig
+type structuredEnvironment struct {
+	Env       string `yaml:"env"`
+	Project   string `yaml:"project,omitempty"`
+	Cluster   string `yaml:"cluster,omitempty"`
+	Zone      string `yaml:"zone,omitempty"`
+	Context   string `yaml:"context,omitempty"`
+	Namespace string `yaml:"namespace"`
+	Protected bool   `yaml:"protected,omitempty"`
+
+	Account     string `yaml:"account,omitempty"`
+	Impersonate string `yaml:"impersonate,omitempty"`
+	Credentials string `yaml:"credentials,omitempty"`
+
+	Proxy  string `yaml:"proxy,omitempty"`
+	Tunnel string `yaml:"tunnel,omitempty"`
+
+	Timeout string `yaml:"timeout,omitempty"`
+	Retries *int   `yaml:"retries,omitempty"`
+}
+
+// structuredCommand is a custom command as written to a structured configuration file
+type structuredCommand struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Target      string   `yaml:"target,omitempty"`
+	Command     string   `yaml:"command,omitempty"`
+	Exec        string   `yaml:"exec,omitempty"`
+	Envs        []string `yaml:"envs,omitempty"`
+	Mutating    bool     `yaml:"mutating,omitempty"`
+}
+
+// writeStructuredConfig writes configs and commands in the YAML format
+func writeStructuredConfig(path string, configs []ClusterConfig, commands []customCommand) error {
+	var data bytes.Buffer
+	if err := writeStructuredConfigTo(&data, configs, commands); err != nil {
+		return err
+	}
+	return writeConfigFile(path, data.Bytes())
+}
+
+// writeConfigFile replaces a configuration file, creating parent directories.
+// The data goes to a temporary file that is renamed over path, so a failed
+// write leaves the existing file intact.
+func writeConfigFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// formatTimeout renders a configured timeout, leaving unset ones empty
+func formatTimeout(timeout time.Duration) string {
+	if timeout == 0 {
+		return ""
+	}
+	return timeout.String()
+}
+
+// writeStructuredConfigTo encodes configs and commands in the YAML format
+func writeStructuredConfigTo(w io.Writer, configs []ClusterConfig, commands []customCommand) error {
+	doc := struct {
+		Environments []structuredEnvironment `yaml:"environments"`
+		Commands     []structuredCommand     `yaml:"commands,omitempty"`
+	}{}
+	for _, c := range configs {
+		doc.Environments = append(doc.Environments, structuredEnvironment{
+			Env:       c.env,
+			Project:   c.project,
+			Cluster:   c.cluster,
+			Zone:      c.zone,
+			Context:   c.kubeContext,
+			Namespace: c.namespace,
+			Protected: c.protected,
+
+			Account:     c.account,
+			Impersonate: c.impersonate,
+			Credentials: c.credentialsFile,
+
+			Proxy:  c.proxy,
+			Tunnel: c.tunnel,
+
+			Timeout: formatTimeout(c.timeout),
+			Retries: c.retries,
+		})
+	}
+
+	for _, c := range commands {
+		target := c.target
+		if target == targetNone {
+			target = ""
+		}
+		doc.Commands = append(doc.Commands, structuredCommand{
+			Name:        c.name,
+			Description: c.description,
+			Target:      target,
+			Command:     c.command,
+			Exec:        c.exec,
+			Envs:        c.envs,
+			Mutating:    c.mutating,
+		})
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
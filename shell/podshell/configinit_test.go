@@ -0,0 +1,102 @@
+package podshell
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withStdin feeds input to the prompts read from os.Stdin during the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		file.Close()
+	})
+}
+
+var testDiscovery = StaticDiscovery{
+	ClusterList: []DiscoveredCluster{
+		{Project: "shop-prod-123", Name: "main", Location: "europe-west1", Status: "RUNNING"},
+		{Project: "shop-dev-123", Name: "main", Location: "us-central1-a", Status: "RUNNING"},
+	},
+	NamespacesByCluster: map[string][]string{
+		"shop-dev-123/main": {"default", "shop"},
+	},
+}
+
+func TestInitConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// Clusters are listed by project: dev first. The prod cluster has no
+	// known namespaces, so its namespace is typed.
+	withStdin(t, "all\ndev\n2\nprod\nshop\n")
+
+	a := NewAccessPods(path)
+	if err := a.InitConfig(context.Background(), InitOptions{Path: path, Discovery: testDiscovery}); err != nil {
+		t.Fatal(err)
+	}
+
+	configs, _, err := readConfigurations(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ClusterConfig{
+		{env: "dev", project: "shop-dev-123", cluster: "main", zone: "us-central1-a", namespace: "shop"},
+		{env: "prod", project: "shop-prod-123", cluster: "main", zone: "europe-west1", namespace: "shop", protected: true},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("got %d environments, want %d", len(configs), len(want))
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Errorf("environment %d = %+v, want %+v", i, configs[i], want[i])
+		}
+	}
+}
+
+func TestInitConfigKeepsFileOnInvalidInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	existing := []byte("environments: []\n")
+	if err := os.WriteFile(path, existing, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Both clusters named the same
+	withStdin(t, "1,2\nmain\n1\nmain\n\n")
+
+	a := NewAccessPods(path)
+	err := a.InitConfig(context.Background(), InitOptions{Path: path, Force: true, Discovery: testDiscovery})
+	if Classify(err) != KindInvalidInput {
+		t.Fatalf("err = %v, want invalid input", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(existing) {
+		t.Errorf("file was overwritten:\n%s", data)
+	}
+}
+
+func TestInitConfigRejectsLineFormatPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.conf")
+	a := NewAccessPods(path)
+	err := a.InitConfig(context.Background(), InitOptions{Path: path, Discovery: testDiscovery})
+	if Classify(err) != KindInvalidInput {
+		t.Fatalf("err = %v, want invalid input", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was created", path)
+	}
+}
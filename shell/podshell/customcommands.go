@@ -0,0 +1,335 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Targets a custom command can run against
+const (
+	targetNone     = "none"
+	targetPod      = "pod"
+	targetWorkload = "workload"
+)
+
+// customCommandCategory is the menu section of commands from the configuration
+const customCommandCategory = "Custom"
+
+// customCommand is a menu action declared in the configuration file:
+//
+//	commands:
+//	  - name: healthz
+//	    description: Check the health endpoint
+//	    target: pod # pod, workload or none
+//	    exec: curl -s localhost:8080/healthz # run inside the selected pod
+//	  - name: psql
+//	    envs: [dev, staging] # optional; default every environment
+//	    command: kubectl --context {{quote .Context}} port-forward -n {{quote .Namespace}} svc/db 5432 & sleep 2; psql -h localhost; kill %1
+//
+// command runs locally and exec inside the target, both with sh -c after
+// rendering as a Go template with commandVars. Values come from arbitrary
+// configuration strings and the cluster, so templates should quote them.
+type customCommand struct {
+	name        string
+	description string
+	target      string
+	command     string
+	exec        string
+	envs        []string
+	mutating    bool
+	line        int
+}
+
+// commandVars are the session variables available to command templates
+type commandVars struct {
+	Env       string
+	Project   string
+	Cluster   string
+	Zone      string
+	Context   string
+	Namespace string
+	Pod       string // Selected pod, with target pod
+	Container string // Selected container, with target pod
+	Kind      string // deployment or statefulset, with target workload
+	Workload  string // Selected workload, with target workload
+}
+
+// templateFuncs are the functions available to command templates
+var templateFuncs = template.FuncMap{"quote": shellQuote}
+
+// shellQuote quotes a value as a single sh word
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// render executes a command template with the session variables
+func render(name, text string, vars commandVars) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// parseCustomCommand parses one item of the commands list
+func parseCustomCommand(item *yaml.Node, report func(*yaml.Node, string, ...interface{})) (customCommand, bool) {
+	if item.Kind != yaml.MappingNode {
+		report(item, "command must be a mapping")
+		return customCommand{}, false
+	}
+
+	c := customCommand{target: targetNone, line: item.Line}
+	valid := true
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		key, value := item.Content[i], item.Content[i+1]
+		var field *string
+		switch key.Value {
+		case "name":
+			field = &c.name
+		case "description":
+			field = &c.description
+		case "target":
+			field = &c.target
+		case "command":
+			field = &c.command
+		case "exec":
+			field = &c.exec
+		case "envs":
+			if err := value.Decode(&c.envs); err != nil {
+				report(value, "envs must be a list of environment names")
+				valid = false
+			}
+			continue
+		case "mutating":
+			if err := value.Decode(&c.mutating); err != nil {
+				report(value, "mutating must be true or false")
+				valid = false
+			}
+			continue
+		default:
+			report(key, "unknown key %q", key.Value)
+			valid = false
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			report(value, "%s must be a string", key.Value)
+			valid = false
+			continue
+		}
+		*field = strings.TrimSpace(value.Value)
+	}
+
+	problem := func(format string, args ...interface{}) {
+		report(item, format, args...)
+		valid = false
+	}
+	if c.name == "" {
+		problem("missing required field: name")
+	}
+	switch c.target {
+	case targetNone, targetPod, targetWorkload:
+	default:
+		problem("invalid target %q: expected pod, workload or none", c.target)
+	}
+	switch {
+	case (c.command == "") == (c.exec == ""):
+		problem("command %s needs exactly one of command or exec", c.name)
+	case c.exec != "" && c.target == targetNone:
+		problem("command %s: exec needs target pod or workload", c.name)
+	}
+
+	// Render with sample values to catch syntax errors and unknown variables
+	sample := commandVars{"env", "project", "cluster", "zone", "context", "namespace", "pod", "container", "deployment", "workload"}
+	for _, text := range []string{c.command, c.exec} {
+		if _, err := render(c.name, text, sample); text != "" && err != nil {
+			problem("invalid template: %v", err)
+		}
+	}
+	return c, valid
+}
+
+// isRegisteredAction reports whether name is taken by a built-in action or
+// one registered with Register so far
+func isRegisteredAction(name string) bool {
+	if _, ok := builtinActions[name]; ok {
+		return true
+	}
+	_, ok := defaultRegistry.Lookup(name)
+	return ok
+}
+
+// mergeCustomCommands overlays layer on base by command name
+func mergeCustomCommands(base, layer []customCommand) []customCommand {
+	for _, c := range layer {
+		replaced := false
+		for i := range base {
+			if base[i].name == c.name {
+				base[i], replaced = c, true
+				break
+			}
+		}
+		if !replaced {
+			base = append(base, c)
+		}
+	}
+	return base
+}
+
+// appliesTo reports whether the command is offered in an environment
+func (c customCommand) appliesTo(env string) bool {
+	if len(c.envs) == 0 {
+		return true
+	}
+	for _, e := range c.envs {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// permissions returns what the command needs to pick its target and exec into it.
+// What a command line does locally cannot be known.
+func (c customCommand) permissions() []Permission {
+	var perms []Permission
+	switch c.target {
+	case targetPod:
+		perms = append(perms, permListPods)
+	case targetWorkload:
+		perms = append(perms, permListDeploys, permListStateful)
+	}
+	if c.exec != "" {
+		perms = append(perms, Permission{Verb: "create", Resource: "pods", Subresource: "exec"})
+	}
+	return perms
+}
+
+// registerCustomCommands adds the configured commands of an environment to the menu
+func (a *AccessPods) registerCustomCommands(config ClusterConfig, commands []customCommand) error {
+	for _, c := range commands {
+		if !c.appliesTo(config.env) {
+			continue
+		}
+		err := a.Commands.Register(Action{
+			Name:        c.name,
+			Description: c.description,
+			Category:    customCommandCategory,
+			Mutating:    c.mutating,
+			Permissions: c.permissions(),
+			Handler: func(ctx context.Context, s *Session) error {
+				return a.runCustomCommand(ctx, c, s.Namespace)
+			},
+		})
+		if err != nil {
+			return &Error{Kind: KindInvalidInput, Err: err, Hint: "rename the command in the configuration file"}
+		}
+	}
+	return nil
+}
+
+// runCustomCommand selects the command's target, renders it and runs it
+// interactively. Like pod shells it is not bounded by the operation timeout.
+func (a *AccessPods) runCustomCommand(ctx context.Context, c customCommand, namespace string) error {
+	vars := commandVars{
+		Env:       a.config.env,
+		Project:   a.config.project,
+		Cluster:   a.config.cluster,
+		Zone:      a.config.zone,
+		Context:   a.config.kubeContext,
+		Namespace: namespace,
+	}
+	switch c.target {
+	case targetPod:
+		pod, err := selectPodObject(ctx, a.client, namespace)
+		if err != nil {
+			return err
+		}
+		if vars.Container, err = selectContainer(pod); err != nil {
+			return err
+		}
+		vars.Pod = pod.Name
+	case targetWorkload:
+		var err error
+		if vars.Kind, vars.Workload, err = selectWorkload(ctx, a.client, namespace); err != nil {
+			return err
+		}
+	}
+
+	var cmd *exec.Cmd
+	if c.exec != "" {
+		script, err := render(c.name, c.exec, vars)
+		if err != nil {
+			return invalidInput("command %s: %v", c.name, err)
+		}
+		args := []string{"exec", "-it", "-n", namespace}
+		if c.target == targetPod {
+			args = append(args, vars.Pod, "-c", vars.Container)
+		} else {
+			args = append(args, vars.Kind+"/"+vars.Workload)
+		}
+		args = append(args, "--", "sh", "-c", script)
+		cmd = kubectlCommand(ctx, a.config, args...)
+	} else {
+		line, err := render(c.name, c.command, vars)
+		if err != nil {
+			return invalidInput("command %s: %v", c.name, err)
+		}
+		cmd = exec.CommandContext(ctx, "sh", "-c", line)
+		cmd.Env = commandEnv(a.config)
+	}
+
+	fmt.Printf("\n%sRunning %s%s\n", colorYellow, c.name, colorReset)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// selectWorkload lets the user pick a Deployment or StatefulSet of the namespace
+func selectWorkload(ctx context.Context, client kubernetes.Interface, namespace string) (string, string, error) {
+	type workload struct{ kind, name string }
+	var workloads []workload
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	for _, d := range deployments.Items {
+		workloads = append(workloads, workload{"deployment", d.Name})
+	}
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	for _, s := range statefulSets.Items {
+		workloads = append(workloads, workload{"statefulset", s.Name})
+	}
+	if len(workloads) == 0 {
+		return "", "", fmt.Errorf("no deployments or statefulsets found in namespace %s", namespace)
+	}
+
+	fmt.Printf("\n%sAvailable workloads:%s\n", colorYellow, colorReset)
+	for i, w := range workloads {
+		fmt.Printf("%d. %s/%s\n", i+1, w.kind, w.name)
+	}
+	var choice int
+	fmt.Printf("\nSelect workload (1-%d): ", len(workloads))
+	fmt.Scan(&choice)
+	if choice < 1 || choice > len(workloads) {
+		return "", "", invalidInput("invalid workload selection")
+	}
+	w := workloads[choice-1]
+	return w.kind, w.name, nil
+}
@@ -0,0 +1,357 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Severity levels used to rank diagnosis findings (higher is more likely the root cause)
+const (
+	severityLow = iota
+	severityMedium
+	severityHigh
+	severityCritical
+)
+
+// maxDiagnoseEvents limits how many recent events are printed in the report
+const maxDiagnoseEvents = 10
+
+// finding is a single likely cause discovered while diagnosing a pod
+type finding struct {
+	Severity int    `json:"severity"` // Higher ranks first
+	Cause    string `json:"cause"`    // Short cause name (e.g. CrashLoopBackOff)
+	Detail   string `json:"detail"`   // What was observed
+	Hint     string `json:"hint"`     // Suggested next step
+}
+
+// podDiagnosis collects everything gathered about a pod for the report
+type podDiagnosis struct {
+	Pod      *corev1.Pod
+	Events   []corev1.Event
+	Node     *corev1.Node
+	Findings []finding
+}
+
+// diagnosePod gathers status, events and node conditions for a selected pod
+// and prints a ranked summary of likely failure causes.
+func (a *AccessPods) diagnosePod(ctx context.Context, namespace string) error {
+	pod, err := selectPodObject(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+
+	diag, err := gatherDiagnosis(ctx, a.client, pod)
+	if err != nil {
+		return err
+	}
+	printDiagnosis(os.Stdout, diag)
+	return nil
+}
+
+// gatherDiagnosis fetches events and the hosting node for a pod and analyzes them.
+// Node lookup failures are tolerated since view-only users often cannot read nodes.
+func gatherDiagnosis(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) (*podDiagnosis, error) {
+	diag := &podDiagnosis{Pod: pod}
+
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod.Name,
+	}.AsSelector().String()
+	events, err := client.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %v", err)
+	}
+	diag.Events = events.Items
+	sort.Slice(diag.Events, func(i, j int) bool {
+		return eventTime(diag.Events[i]).After(eventTime(diag.Events[j]))
+	})
+
+	if pod.Spec.NodeName != "" {
+		if node, err := client.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{}); err == nil {
+			diag.Node = node
+		}
+	}
+
+	diag.Findings = analyzePod(diag)
+	return diag, nil
+}
+
+// analyzePod inspects the gathered data and returns findings ranked by severity
+func analyzePod(diag *podDiagnosis) []finding {
+	var findings []finding
+	pod := diag.Pod
+
+	if pod.Status.Reason == "Evicted" {
+		findings = append(findings, finding{
+			Severity: severityCritical,
+			Cause:    "Evicted",
+			Detail:   pod.Status.Message,
+			Hint:     "Check node pressure conditions and the pod's resource requests",
+		})
+	}
+
+	// Scheduling problems
+	if pod.Status.Phase == corev1.PodPending {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type != corev1.PodScheduled || cond.Status != corev1.ConditionFalse {
+				continue
+			}
+			f := finding{
+				Severity: severityCritical,
+				Cause:    "Pending (unschedulable)",
+				Detail:   cond.Message,
+				Hint:     "Check node selectors, taints/tolerations and affinity rules",
+			}
+			if strings.Contains(cond.Message, "Insufficient") {
+				f.Cause = "Pending due to insufficient resources"
+				f.Hint = "Lower the pod's requests or add capacity to the node pool"
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	// Container states
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		findings = append(findings, analyzeContainer(cs)...)
+	}
+
+	// Events not already covered by container state
+	probeFailures := map[string]int{}
+	for _, ev := range diag.Events {
+		if ev.Type != corev1.EventTypeWarning {
+			continue
+		}
+		switch ev.Reason {
+		case "Unhealthy":
+			probeFailures[probeKind(ev.Message)] += int(max(ev.Count, 1))
+		case "FailedMount", "FailedAttachVolume":
+			findings = append(findings, finding{
+				Severity: severityHigh,
+				Cause:    "Volume mount failure",
+				Detail:   ev.Message,
+				Hint:     "Verify referenced PVCs, ConfigMaps and Secrets exist",
+			})
+		case "FailedCreatePodSandBox":
+			findings = append(findings, finding{
+				Severity: severityHigh,
+				Cause:    "Pod sandbox creation failed",
+				Detail:   ev.Message,
+				Hint:     "Usually a node networking (CNI) problem; check the node",
+			})
+		}
+	}
+	for kind, count := range probeFailures {
+		severity := severityMedium
+		hint := "Check the probe endpoint, timeouts and initialDelaySeconds"
+		if kind == "Liveness" {
+			severity = severityHigh
+			hint = "Failing liveness probes restart the container; " + hint
+		}
+		findings = append(findings, finding{
+			Severity: severity,
+			Cause:    kind + " probe failures",
+			Detail:   fmt.Sprintf("%d failure(s) reported in recent events", count),
+			Hint:     hint,
+		})
+	}
+
+	// Node conditions
+	if node := diag.Node; node != nil {
+		for _, cond := range node.Status.Conditions {
+			switch {
+			case cond.Type == corev1.NodeReady && cond.Status != corev1.ConditionTrue:
+				findings = append(findings, finding{
+					Severity: severityHigh,
+					Cause:    "Node not ready",
+					Detail:   fmt.Sprintf("%s: %s", node.Name, cond.Message),
+					Hint:     "The pod may be rescheduled once the node is replaced",
+				})
+			case cond.Type != corev1.NodeReady && cond.Status == corev1.ConditionTrue:
+				findings = append(findings, finding{
+					Severity: severityMedium,
+					Cause:    "Node " + string(cond.Type),
+					Detail:   fmt.Sprintf("%s: %s", node.Name, cond.Message),
+					Hint:     "Pods on this node may be evicted or throttled",
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Severity > findings[j].Severity })
+	return findings
+}
+
+// analyzeContainer returns findings for a single container status
+func analyzeContainer(cs corev1.ContainerStatus) []finding {
+	var findings []finding
+	name := cs.Name
+
+	if waiting := cs.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case "CrashLoopBackOff":
+			detail := fmt.Sprintf("container %s restarted %d time(s)", name, cs.RestartCount)
+			if last := cs.LastTerminationState.Terminated; last != nil {
+				detail += fmt.Sprintf("; last exit %d (%s)", last.ExitCode, last.Reason)
+			}
+			findings = append(findings, finding{
+				Severity: severityCritical,
+				Cause:    "CrashLoopBackOff",
+				Detail:   detail,
+				Hint:     "Check the previous logs: kubectl logs --previous",
+			})
+		case "ImagePullBackOff", "ErrImagePull", "InvalidImageName":
+			findings = append(findings, finding{
+				Severity: severityCritical,
+				Cause:    waiting.Reason,
+				Detail:   fmt.Sprintf("container %s: %s", name, waiting.Message),
+				Hint:     "Verify the image tag exists and the node can pull from the registry",
+			})
+		case "CreateContainerConfigError", "CreateContainerError":
+			findings = append(findings, finding{
+				Severity: severityCritical,
+				Cause:    waiting.Reason,
+				Detail:   fmt.Sprintf("container %s: %s", name, waiting.Message),
+				Hint:     "Usually a missing ConfigMap/Secret key referenced by the container",
+			})
+		}
+	}
+
+	oomKilled := func(t *corev1.ContainerStateTerminated) bool {
+		return t != nil && t.Reason == "OOMKilled"
+	}
+	if oomKilled(cs.State.Terminated) || oomKilled(cs.LastTerminationState.Terminated) {
+		findings = append(findings, finding{
+			Severity: severityHigh,
+			Cause:    "OOMKilled",
+			Detail:   fmt.Sprintf("container %s exceeded its memory limit", name),
+			Hint:     "Raise the memory limit or investigate memory usage",
+		})
+	} else if last := cs.LastTerminationState.Terminated; last != nil && last.ExitCode != 0 {
+		findings = append(findings, finding{
+			Severity: severityLow,
+			Cause:    "Previous termination",
+			Detail:   fmt.Sprintf("container %s exited %d (%s) at %s", name, last.ExitCode, last.Reason, last.FinishedAt.Format(time.RFC3339)),
+			Hint:     "Check the previous logs: kubectl logs --previous",
+		})
+	}
+
+	if cs.State.Running != nil && !cs.Ready {
+		findings = append(findings, finding{
+			Severity: severityMedium,
+			Cause:    "Container not ready",
+			Detail:   fmt.Sprintf("container %s is running but failing readiness", name),
+			Hint:     "Check the readiness probe and application startup",
+		})
+	}
+	return findings
+}
+
+// probeKind extracts the probe type (Liveness, Readiness, Startup) from an Unhealthy event message
+func probeKind(message string) string {
+	for _, kind := range []string{"Liveness", "Readiness", "Startup"} {
+		if strings.HasPrefix(message, kind) {
+			return kind
+		}
+	}
+	return "Health"
+}
+
+// eventTime returns the most relevant timestamp of an event
+func eventTime(ev corev1.Event) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+// printDiagnosis prints the gathered pod information followed by ranked findings
+func printDiagnosis(out io.Writer, diag *podDiagnosis) {
+	pod := diag.Pod
+
+	fmt.Fprintf(out, "\n%sPod status:%s\n", colorYellow, colorReset)
+	fmt.Fprintf(out, "Name: %s\n", pod.Name)
+	fmt.Fprintf(out, "Phase: %s\n", pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		fmt.Fprintf(out, "Reason: %s\n", pod.Status.Reason)
+	}
+	fmt.Fprintf(out, "Node: %s\n", pod.Spec.NodeName)
+
+	fmt.Fprintf(out, "\n%sContainers:%s\n", colorYellow, colorReset)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY\tRESTARTS\tSTATE\tLAST TERMINATION")
+	for _, cs := range pod.Status.ContainerStatuses {
+		last := "-"
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			last = fmt.Sprintf("%s (exit %d)", t.Reason, t.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%t\t%d\t%s\t%s\n", cs.Name, cs.Ready, cs.RestartCount, containerState(cs.State), last)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%sRecent events:%s\n", colorYellow, colorReset)
+	if len(diag.Events) == 0 {
+		fmt.Fprintln(out, "No events found")
+	}
+	for i, ev := range diag.Events {
+		if i == maxDiagnoseEvents {
+			break
+		}
+		color := colorReset
+		if ev.Type == corev1.EventTypeWarning {
+			color = colorRed
+		}
+		fmt.Fprintf(out, "%s%s %s %s: %s%s\n", color, eventTime(ev).Format(time.RFC3339), ev.Type, ev.Reason, ev.Message, colorReset)
+	}
+
+	if diag.Node != nil {
+		fmt.Fprintf(out, "\n%sNode conditions (%s):%s\n", colorYellow, diag.Node.Name, colorReset)
+		for _, cond := range diag.Node.Status.Conditions {
+			fmt.Fprintf(out, "%s=%s\n", cond.Type, cond.Status)
+		}
+	}
+
+	fmt.Fprintf(out, "\n%sLikely causes:%s\n", colorYellow, colorReset)
+	if len(diag.Findings) == 0 {
+		fmt.Fprintf(out, "%sNo problems detected%s\n", colorGreen, colorReset)
+		return
+	}
+	for i, f := range diag.Findings {
+		color := colorYellow
+		if f.Severity >= severityHigh {
+			color = colorRed
+		}
+		fmt.Fprintf(out, "%d. %s%s%s\n", i+1, color, f.Cause, colorReset)
+		if f.Detail != "" {
+			fmt.Fprintf(out, "   %s\n", f.Detail)
+		}
+		fmt.Fprintf(out, "   Hint: %s\n", f.Hint)
+	}
+}
+
+// containerState renders a container state as a short string
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return "Waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		return "Terminated: " + state.Terminated.Reason
+	case state.Running != nil:
+		return "Running"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,141 @@
+package podshell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Configuration discovery locations
+const (
+	configEnvVar      = "GO_GCP_CONFIG"
+	systemConfigPath  = "/etc/go-gcp/config"
+	projectConfigPath = ".go-gcp.yaml"
+)
+
+// ConfigSource is a candidate configuration file and whether it was found
+type ConfigSource struct {
+	Layer string // flag, env, system, user or project
+	Path  string
+	Found bool
+}
+
+// DiscoverConfig returns the configuration files considered, lowest precedence
+// first. An explicit path (the -f flag) is used on its own; otherwise the
+// GO_GCP_CONFIG list is used if set, and the system, user and project files
+// are layered when it is not.
+func DiscoverConfig(explicit string) []ConfigSource {
+	var sources []ConfigSource
+	switch {
+	case explicit != "":
+		sources = append(sources, ConfigSource{Layer: "flag", Path: explicit})
+	case os.Getenv(configEnvVar) != "":
+		for _, path := range filepath.SplitList(os.Getenv(configEnvVar)) {
+			if path != "" {
+				sources = append(sources, ConfigSource{Layer: "env", Path: path})
+			}
+		}
+	default:
+		sources = append(sources, ConfigSource{Layer: "system", Path: systemConfigPath})
+		if dir := xdgConfigHome(); dir != "" {
+			sources = append(sources, ConfigSource{Layer: "user", Path: filepath.Join(dir, "go-gcp", "config")})
+		}
+		sources = append(sources, ConfigSource{Layer: "project", Path: projectConfigPath})
+	}
+
+	for i := range sources {
+		info, err := os.Stat(sources[i].Path)
+		sources[i].Found = err == nil && !info.IsDir()
+	}
+	return sources
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config
+func xdgConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// configurations loads and merges the environments of the discovered configuration files
+func (a *AccessPods) configurations() ([]ClusterConfig, error) {
+	configs, _, err := a.configurationsWithCommands()
+	return configs, err
+}
+
+// configurationsWithCommands loads and merges the discovered configuration
+// files, returning their environments and custom commands
+func (a *AccessPods) configurationsWithCommands() ([]ClusterConfig, []customCommand, error) {
+	configs, commands, err := loadConfigurations(DiscoverConfig(a.FilePath))
+	if err != nil {
+		return nil, nil, err
+	}
+	// Environments without their own timeout or retries use the session defaults
+	for i := range configs {
+		if configs[i].timeout == 0 {
+			configs[i].timeout = a.Timeout
+		}
+		if configs[i].retries == nil {
+			retries := a.Retries
+			configs[i].retries = &retries
+		}
+		configs[i].verbose = a.Verbose
+	}
+	return configs, commands, nil
+}
+
+// loadConfigurations merges the found sources in order. An environment or
+// custom command defined in a later layer replaces the one with the same name
+// from an earlier layer.
+func loadConfigurations(sources []ConfigSource) ([]ClusterConfig, []customCommand, error) {
+	var configs []ClusterConfig
+	var commands []customCommand
+	loaded := 0
+	for _, source := range sources {
+		if !source.Found {
+			continue
+		}
+		layer, layerCommands, err := readConfigurations(source.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		loaded++
+		configs = mergeConfigurations(configs, layer)
+		commands = mergeCustomCommands(commands, layerCommands)
+	}
+
+	if loaded == 0 {
+		var paths []string
+		for _, source := range sources {
+			paths = append(paths, source.Path)
+		}
+		return nil, nil, fmt.Errorf("no configuration file found (looked for %s); use -f or set %s",
+			strings.Join(paths, ", "), configEnvVar)
+	}
+	return configs, commands, nil
+}
+
+// mergeConfigurations overlays layer on base: environments with a name already
+// in base replace it in place, new ones are appended
+func mergeConfigurations(base, layer []ClusterConfig) []ClusterConfig {
+	index := make(map[string]int, len(base))
+	for i, c := range base {
+		if _, ok := index[c.env]; !ok {
+			index[c.env] = i
+		}
+	}
+	for _, c := range layer {
+		if i, ok := index[c.env]; ok {
+			base[i] = c
+			continue
+		}
+		base = append(base, c)
+	}
+	return base
+}
@@ -0,0 +1,355 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxDriftValueWidth truncates long values (e.g. ConfigMap data) in the table view, in runes
+const maxDriftValueWidth = 60
+
+// DiffOptions configures a drift comparison between two environments
+type DiffOptions struct {
+	Left      string   // Environment name of the left side (e.g. staging)
+	Right     string   // Environment name of the right side (e.g. prod)
+	Namespace string   // Namespace override; empty uses each environment's namespace
+	Kinds     []string // Subset of DriftKinds to compare; empty compares all
+	Output    string   // Output format: table, wide, json or yaml
+}
+
+// driftFlattener turns the objects of one kind into comparable field maps keyed by object name
+type driftFlattener func(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]map[string]string, error)
+
+// driftKinds maps the compared kinds to their flattener
+var driftKinds = map[string]driftFlattener{
+	"deployments": flattenDeployments,
+	"configmaps":  flattenConfigMaps,
+	"services":    flattenServices,
+}
+
+// DriftKinds returns the resource kinds compared by Diff
+func DriftKinds() []string {
+	names := make([]string, 0, len(driftKinds))
+	for name := range driftKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// driftItem is a single differing field; an empty side means the field or object is absent
+type driftItem struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Field  string `json:"field"`
+	Left   string `json:"left"`
+	Right  string `json:"right"`
+	Status string `json:"status"` // changed, only-left or only-right
+}
+
+// driftReport is the result of comparing two environments
+type driftReport struct {
+	Kind        string      `json:"kind"`
+	Left        string      `json:"left"`
+	Right       string      `json:"right"`
+	Differences []driftItem `json:"differences"`
+}
+
+// environmentSnapshot holds the flattened objects of one environment
+type environmentSnapshot map[string]map[string]map[string]string // kind -> name -> field -> value
+
+// Diff compares Deployments, ConfigMaps and Services between two environments
+// after normalizing server-populated fields, and prints the differences.
+func (a *AccessPods) Diff(ctx context.Context, opts DiffOptions) error {
+	format, err := parseOutputFormat(opts.Output)
+	if err != nil {
+		return err
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = DriftKinds()
+	}
+	for _, kind := range kinds {
+		if _, ok := driftKinds[kind]; !ok {
+			return invalidInput("unknown kind %q (expected one of %s)", kind, strings.Join(DriftKinds(), ", "))
+		}
+	}
+
+	configs, err := a.configurations()
+	if err != nil {
+		return err
+	}
+	left, err := findConfiguration(configs, opts.Left)
+	if err != nil {
+		return err
+	}
+	right, err := findConfiguration(configs, opts.Right)
+	if err != nil {
+		return err
+	}
+	if opts.Namespace != "" {
+		left.namespace = opts.Namespace
+		right.namespace = opts.Namespace
+	}
+
+	// Snapshot both environments concurrently
+	var snapshots [2]environmentSnapshot
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i, config := range []ClusterConfig{left, right} {
+		wg.Add(1)
+		go func(i int, config ClusterConfig) {
+			defer wg.Done()
+			snapshots[i], errs[i] = snapshotEnvironment(ctx, config, kinds)
+		}(i, config)
+	}
+	wg.Wait()
+	for i, config := range []ClusterConfig{left, right} {
+		if errs[i] != nil {
+			return fmt.Errorf("%s: %w", config.env, errs[i])
+		}
+	}
+
+	report := &driftReport{
+		Kind:        "DriftReport",
+		Left:        fmt.Sprintf("%s/%s", left.env, left.namespace),
+		Right:       fmt.Sprintf("%s/%s", right.env, right.namespace),
+		Differences: compareSnapshots(snapshots[0], snapshots[1]),
+	}
+	return writeOutput(os.Stdout, format, report)
+}
+
+// snapshotEnvironment connects to an environment and flattens the requested kinds
+func snapshotEnvironment(ctx context.Context, config ClusterConfig, kinds []string) (environmentSnapshot, error) {
+	client, cleanup, err := connectToEnvironment(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	snapshot := environmentSnapshot{}
+	for _, kind := range kinds {
+		objects, err := driftKinds[kind](ctx, client, config.namespace)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		snapshot[kind] = objects
+	}
+	return snapshot, nil
+}
+
+// compareSnapshots returns the differing fields, sorted by kind, name and field
+func compareSnapshots(left, right environmentSnapshot) []driftItem {
+	diffs := []driftItem{}
+	for kind := range unionKeys(left, right) {
+		for name := range unionKeys(left[kind], right[kind]) {
+			l, lok := left[kind][name]
+			r, rok := right[kind][name]
+			switch {
+			case !rok:
+				diffs = append(diffs, driftItem{Kind: kind, Name: name, Field: "(object)", Left: "present", Status: "only-left"})
+				continue
+			case !lok:
+				diffs = append(diffs, driftItem{Kind: kind, Name: name, Field: "(object)", Right: "present", Status: "only-right"})
+				continue
+			}
+			for field := range unionKeys(l, r) {
+				lv, lok := l[field]
+				rv, rok := r[field]
+				switch {
+				case lok && rok && lv == rv:
+				case !rok:
+					diffs = append(diffs, driftItem{kind, name, field, lv, "", "only-left"})
+				case !lok:
+					diffs = append(diffs, driftItem{kind, name, field, "", rv, "only-right"})
+				default:
+					diffs = append(diffs, driftItem{kind, name, field, lv, rv, "changed"})
+				}
+			}
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Kind != diffs[j].Kind {
+			return diffs[i].Kind < diffs[j].Kind
+		}
+		if diffs[i].Name != diffs[j].Name {
+			return diffs[i].Name < diffs[j].Name
+		}
+		return diffs[i].Field < diffs[j].Field
+	})
+	return diffs
+}
+
+// unionKeys returns the set of keys present in either map
+func unionKeys[V any](a, b map[string]V) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+// flattenDeployments reduces deployments to replicas, labels, images, env and resources.
+// Server-populated metadata, annotations and status are dropped by construction.
+func flattenDeployments(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]map[string]string, error) {
+	list, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]map[string]string, len(list.Items))
+	for _, d := range list.Items {
+		objects[d.Name] = flattenDeployment(&d)
+	}
+	return objects, nil
+}
+
+func flattenDeployment(d *appsv1.Deployment) map[string]string {
+	fields := map[string]string{"replicas": fmt.Sprint(replicasOrDefault(d.Spec.Replicas))}
+	addLabels(fields, "labels", d.Labels)
+	addLabels(fields, "podLabels", d.Spec.Template.Labels)
+	for _, c := range d.Spec.Template.Spec.Containers {
+		prefix := "container/" + c.Name
+		fields[prefix+"/image"] = c.Image
+		for _, e := range c.Env {
+			fields[prefix+"/env/"+e.Name] = envSpecValue(e)
+		}
+		for _, from := range c.EnvFrom {
+			switch {
+			case from.ConfigMapRef != nil:
+				fields[prefix+"/envFrom/configmap/"+from.ConfigMapRef.Name] = from.Prefix
+			case from.SecretRef != nil:
+				fields[prefix+"/envFrom/secret/"+from.SecretRef.Name] = from.Prefix
+			}
+		}
+		for name, q := range c.Resources.Requests {
+			fields[prefix+"/resources/requests/"+string(name)] = q.String()
+		}
+		for name, q := range c.Resources.Limits {
+			fields[prefix+"/resources/limits/"+string(name)] = q.String()
+		}
+	}
+	return fields
+}
+
+// envSpecValue describes an env entry without resolving references
+func envSpecValue(e corev1.EnvVar) string {
+	from := e.ValueFrom
+	switch {
+	case from == nil:
+		return e.Value
+	case from.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("configmap:%s/%s", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key)
+	case from.SecretKeyRef != nil:
+		return fmt.Sprintf("secret:%s/%s", from.SecretKeyRef.Name, from.SecretKeyRef.Key)
+	case from.FieldRef != nil:
+		return "field:" + from.FieldRef.FieldPath
+	case from.ResourceFieldRef != nil:
+		return "resource:" + from.ResourceFieldRef.Resource
+	}
+	return ""
+}
+
+// flattenConfigMaps reduces ConfigMaps to labels and data entries.
+// The kube-root-ca.crt ConfigMap is cluster-generated and skipped.
+func flattenConfigMaps(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]map[string]string, error) {
+	list, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]map[string]string, len(list.Items))
+	for _, cm := range list.Items {
+		if cm.Name == "kube-root-ca.crt" {
+			continue
+		}
+		fields := map[string]string{}
+		addLabels(fields, "labels", cm.Labels)
+		for k, v := range cm.Data {
+			fields["data/"+k] = v
+		}
+		for k, v := range cm.BinaryData {
+			fields["binaryData/"+k] = fmt.Sprintf("<%d bytes>", len(v))
+		}
+		objects[cm.Name] = fields
+	}
+	return objects, nil
+}
+
+// flattenServices reduces Services to labels, type, selector and ports.
+// Cluster IPs and allocated node ports are server-populated and ignored.
+func flattenServices(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]map[string]string, error) {
+	list, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]map[string]string, len(list.Items))
+	for _, svc := range list.Items {
+		fields := map[string]string{"type": string(svc.Spec.Type)}
+		addLabels(fields, "labels", svc.Labels)
+		addLabels(fields, "selector", svc.Spec.Selector)
+		for _, p := range svc.Spec.Ports {
+			key := p.Name
+			if key == "" {
+				key = fmt.Sprint(p.Port)
+			}
+			fields["port/"+key] = fmt.Sprintf("%d->%s/%s", p.Port, p.TargetPort.String(), p.Protocol)
+		}
+		objects[svc.Name] = fields
+	}
+	return objects, nil
+}
+
+// addLabels adds each label as its own field under prefix
+func addLabels(fields map[string]string, prefix string, labels map[string]string) {
+	for k, v := range labels {
+		fields[prefix+"/"+k] = v
+	}
+}
+
+func (r *driftReport) writeTable(out io.Writer, wide bool) {
+	fmt.Fprintf(out, "%s--- %s%s\n", colorRed, r.Left, colorReset)
+	fmt.Fprintf(out, "%s+++ %s%s\n", colorGreen, r.Right, colorReset)
+	if len(r.Differences) == 0 {
+		fmt.Fprintf(out, "%sNo drift detected%s\n", colorGreen, colorReset)
+		return
+	}
+
+	truncate := func(s string) string {
+		s = strings.ReplaceAll(s, "\n", "\\n")
+		if runes := []rune(s); !wide && len(runes) > maxDriftValueWidth {
+			return string(runes[:maxDriftValueWidth]) + "..."
+		}
+		return s
+	}
+
+	current := ""
+	for _, d := range r.Differences {
+		if object := d.Kind + "/" + d.Name; object != current {
+			current = object
+			fmt.Fprintf(out, "\n%s%s%s\n", colorYellow, object, colorReset)
+		}
+		switch d.Status {
+		case "only-left":
+			fmt.Fprintf(out, "%s- %s: %s%s\n", colorRed, d.Field, truncate(d.Left), colorReset)
+		case "only-right":
+			fmt.Fprintf(out, "%s+ %s: %s%s\n", colorGreen, d.Field, truncate(d.Right), colorReset)
+		default:
+			fmt.Fprintf(out, "~ %s\n", d.Field)
+			fmt.Fprintf(out, "%s  - %s%s\n", colorRed, truncate(d.Left), colorReset)
+			fmt.Fprintf(out, "%s  + %s%s\n", colorGreen, truncate(d.Right), colorReset)
+		}
+	}
+	fmt.Fprintf(out, "\n%d difference(s)\n", len(r.Differences))
+}
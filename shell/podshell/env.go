@@ -0,0 +1,440 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maskedValue replaces secret-derived values unless the user asks to reveal them
+const maskedValue = "********"
+
+// envVar is a container environment variable resolved from the pod spec
+type envVar struct {
+	Name   string `json:"name"`   // Variable name as seen by the container
+	Value  string `json:"value"`  // Resolved value
+	Source string `json:"source"` // Where the value comes from (e.g. spec, configmap/app, secret/db)
+	Secret bool   `json:"secret"` // Whether the value is derived from a Secret
+}
+
+// envDiff describes a variable that differs between two resolved environments
+type envDiff struct {
+	Name  string
+	Left  *envVar // nil when the variable only exists on the right
+	Right *envVar // nil when the variable only exists on the left
+}
+
+// showPodEnv displays the environment variables declared for a container of a selected pod.
+// Values are resolved from env and envFrom (ConfigMap/Secret refs) and secrets are masked by default.
+func (a *AccessPods) showPodEnv(ctx context.Context, namespace string) error {
+	vars, err := a.selectPodEnv(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+
+	// Prompt for optional filter and secret reveal
+	fmt.Print("\nFilter by name or value (empty for all): ")
+	var filter string
+	fmt.Scanln(&filter)
+	reveal := a.getUserConfirmation("Reveal secret values? (y/n): ")
+
+	printEnv(os.Stdout, filterEnv(vars, filter, reveal), reveal)
+	return nil
+}
+
+// diffPodEnv compares the environment variables of two pods, either within the
+// current namespace or against a pod in another configured environment.
+func (a *AccessPods) diffPodEnv(ctx context.Context, namespace string) error {
+	fmt.Printf("\n%sSelect first pod:%s", colorYellow, colorReset)
+	left, err := a.selectPodEnv(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%sCompare with:%s\n", colorYellow, colorReset)
+	fmt.Println("1. Another pod in this namespace")
+	fmt.Println("2. A pod in another environment")
+	choice := a.getUserInput("Select target (1-2): ")
+
+	var right []envVar
+	switch choice {
+	case 1:
+		right, err = a.selectPodEnv(ctx, a.client, namespace)
+	case 2:
+		right, err = a.selectRemotePodEnv(ctx)
+	default:
+		return invalidInput("invalid comparison target")
+	}
+	if err != nil {
+		return err
+	}
+
+	reveal := a.getUserConfirmation("Reveal secret values? (y/n): ")
+	printEnvDiff(diffEnv(left, right), reveal)
+	return nil
+}
+
+// selectRemotePodEnv connects to another configured environment and resolves
+// the environment of a pod selected there.
+func (a *AccessPods) selectRemotePodEnv(ctx context.Context) ([]envVar, error) {
+	configs, err := a.configurations()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("\n%sAvailable environments:%s\n", colorYellow, colorReset)
+	for i, config := range configs {
+		fmt.Printf("%d. %s\n", i+1, config.env)
+	}
+	choice := a.getUserInput(fmt.Sprintf("Select environment (1-%d): ", len(configs)))
+	if choice < 1 || choice > len(configs) {
+		return nil, invalidInput("invalid environment selection")
+	}
+	config := configs[choice-1]
+
+	client, cleanup, err := connectToEnvironment(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return a.selectPodEnv(ctx, client, config.namespace)
+}
+
+// selectPodEnv lets the user pick a pod and container, then resolves its environment.
+func (a *AccessPods) selectPodEnv(ctx context.Context, client kubernetes.Interface, namespace string) ([]envVar, error) {
+	pod, err := selectPodObject(ctx, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	container, err := selectContainer(pod)
+	if err != nil {
+		return nil, err
+	}
+	return resolvePodEnv(ctx, client, pod, container)
+}
+
+// resolvePodEnv resolves the environment of a container from the pod spec.
+// envFrom sources are applied first, followed by env entries, matching the
+// precedence used by the kubelet. Missing or unreadable references are reported
+// in the source column rather than failing the whole listing.
+func resolvePodEnv(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod, container string) ([]envVar, error) {
+	var spec *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == container {
+			spec = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("container %s not found in pod %s", container, pod.Name)
+	}
+
+	refs := newEnvRefCache(ctx, client, pod.Namespace)
+	resolved := make(map[string]envVar)
+	set := func(v envVar) { resolved[v.Name] = v }
+
+	// Bulk sources from envFrom
+	for _, from := range spec.EnvFrom {
+		switch {
+		case from.ConfigMapRef != nil:
+			source := "configmap/" + from.ConfigMapRef.Name
+			data, err := refs.configMap(from.ConfigMapRef.Name)
+			if err != nil {
+				set(envVar{Name: from.Prefix + "*", Source: fmt.Sprintf("%s (%v)", source, err)})
+				continue
+			}
+			for key, value := range data {
+				set(envVar{Name: from.Prefix + key, Value: value, Source: source})
+			}
+		case from.SecretRef != nil:
+			source := "secret/" + from.SecretRef.Name
+			data, err := refs.secret(from.SecretRef.Name)
+			if err != nil {
+				set(envVar{Name: from.Prefix + "*", Source: fmt.Sprintf("%s (%v)", source, err), Secret: true})
+				continue
+			}
+			for key, value := range data {
+				set(envVar{Name: from.Prefix + key, Value: value, Source: source, Secret: true})
+			}
+		}
+	}
+
+	// Individual env entries override envFrom
+	for _, e := range spec.Env {
+		v := envVar{Name: e.Name, Value: e.Value, Source: "spec"}
+		if from := e.ValueFrom; from != nil {
+			switch {
+			case from.ConfigMapKeyRef != nil:
+				ref := from.ConfigMapKeyRef
+				v.Source = fmt.Sprintf("configmap/%s[%s]", ref.Name, ref.Key)
+				data, err := refs.configMap(ref.Name)
+				if err != nil {
+					v.Source += fmt.Sprintf(" (%v)", err)
+				} else if value, ok := data[ref.Key]; ok {
+					v.Value = value
+				} else {
+					v.Source += " (missing key)"
+				}
+			case from.SecretKeyRef != nil:
+				ref := from.SecretKeyRef
+				v.Secret = true
+				v.Source = fmt.Sprintf("secret/%s[%s]", ref.Name, ref.Key)
+				data, err := refs.secret(ref.Name)
+				if err != nil {
+					v.Source += fmt.Sprintf(" (%v)", err)
+				} else if value, ok := data[ref.Key]; ok {
+					v.Value = value
+				} else {
+					v.Source += " (missing key)"
+				}
+			case from.FieldRef != nil:
+				v.Source = "field/" + from.FieldRef.FieldPath
+				v.Value = podFieldValue(pod, from.FieldRef.FieldPath)
+			case from.ResourceFieldRef != nil:
+				v.Source = "resource/" + from.ResourceFieldRef.Resource
+				v.Value = containerResourceValue(spec, from.ResourceFieldRef.Resource)
+			}
+		}
+		set(v)
+	}
+
+	vars := make([]envVar, 0, len(resolved))
+	for _, v := range resolved {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
+
+// envRefCache fetches referenced ConfigMaps and Secrets once per resolution
+type envRefCache struct {
+	ctx        context.Context
+	client     kubernetes.Interface
+	namespace  string
+	configMaps map[string]map[string]string
+	secrets    map[string]map[string]string
+	errors     map[string]error
+}
+
+func newEnvRefCache(ctx context.Context, client kubernetes.Interface, namespace string) *envRefCache {
+	return &envRefCache{
+		ctx:        ctx,
+		client:     client,
+		namespace:  namespace,
+		configMaps: make(map[string]map[string]string),
+		secrets:    make(map[string]map[string]string),
+		errors:     make(map[string]error),
+	}
+}
+
+func (c *envRefCache) configMap(name string) (map[string]string, error) {
+	key := "configmap/" + name
+	if err, ok := c.errors[key]; ok {
+		return nil, err
+	}
+	if data, ok := c.configMaps[name]; ok {
+		return data, nil
+	}
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(c.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		c.errors[key] = refError(err)
+		return nil, c.errors[key]
+	}
+	c.configMaps[name] = cm.Data
+	return cm.Data, nil
+}
+
+func (c *envRefCache) secret(name string) (map[string]string, error) {
+	key := "secret/" + name
+	if err, ok := c.errors[key]; ok {
+		return nil, err
+	}
+	if data, ok := c.secrets[name]; ok {
+		return data, nil
+	}
+	secret, err := c.client.CoreV1().Secrets(c.namespace).Get(c.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		c.errors[key] = refError(err)
+		return nil, c.errors[key]
+	}
+	data := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	for k, v := range secret.StringData {
+		data[k] = v
+	}
+	c.secrets[name] = data
+	return data, nil
+}
+
+// refError shortens API errors for display in the source column
+func refError(err error) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("not found")
+	case apierrors.IsForbidden(err):
+		return fmt.Errorf("forbidden")
+	}
+	return err
+}
+
+// podFieldValue resolves a downward API field path against the pod
+func podFieldValue(pod *corev1.Pod, path string) string {
+	switch path {
+	case "metadata.name":
+		return pod.Name
+	case "metadata.namespace":
+		return pod.Namespace
+	case "metadata.uid":
+		return string(pod.UID)
+	case "spec.nodeName":
+		return pod.Spec.NodeName
+	case "spec.serviceAccountName":
+		return pod.Spec.ServiceAccountName
+	case "status.hostIP":
+		return pod.Status.HostIP
+	case "status.podIP":
+		return pod.Status.PodIP
+	}
+	if key, ok := fieldSubscript(path, "metadata.labels"); ok {
+		return pod.Labels[key]
+	}
+	if key, ok := fieldSubscript(path, "metadata.annotations"); ok {
+		return pod.Annotations[key]
+	}
+	return ""
+}
+
+// fieldSubscript extracts the key from paths like metadata.labels['app']
+func fieldSubscript(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix+"[") || !strings.HasSuffix(path, "]") {
+		return "", false
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(path, prefix+"["), "]")
+	return strings.Trim(key, `'"`), true
+}
+
+// containerResourceValue resolves a resourceFieldRef such as limits.cpu
+func containerResourceValue(container *corev1.Container, resource string) string {
+	kind, name, ok := strings.Cut(resource, ".")
+	if !ok {
+		return ""
+	}
+	list := container.Resources.Requests
+	if kind == "limits" {
+		list = container.Resources.Limits
+	}
+	if q, ok := list[corev1.ResourceName(name)]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// filterEnv keeps variables whose name, or visible value, contains the filter (case-insensitive)
+func filterEnv(vars []envVar, filter string, reveal bool) []envVar {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return vars
+	}
+	var result []envVar
+	for _, v := range vars {
+		visible := v.Value
+		if v.Secret && !reveal {
+			visible = ""
+		}
+		if strings.Contains(strings.ToLower(v.Name), filter) ||
+			strings.Contains(strings.ToLower(visible), filter) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// displayValue returns the value to print, masking secrets unless revealed
+func (v envVar) displayValue(reveal bool) string {
+	if v.Secret && !reveal && v.Value != "" {
+		return maskedValue
+	}
+	return v.Value
+}
+
+// printEnv prints resolved variables as a NAME / VALUE / SOURCE table
+func printEnv(out io.Writer, vars []envVar, reveal bool) {
+	if len(vars) == 0 {
+		fmt.Fprintln(out, "No environment variables found")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tSOURCE")
+	for _, v := range vars {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, v.displayValue(reveal), v.Source)
+	}
+	w.Flush()
+}
+
+// diffEnv compares two resolved environments and returns the differing variables sorted by name
+func diffEnv(left, right []envVar) []envDiff {
+	leftByName := make(map[string]*envVar, len(left))
+	for i := range left {
+		leftByName[left[i].Name] = &left[i]
+	}
+	rightByName := make(map[string]*envVar, len(right))
+	for i := range right {
+		rightByName[right[i].Name] = &right[i]
+	}
+
+	var diffs []envDiff
+	for name, l := range leftByName {
+		r, ok := rightByName[name]
+		if !ok {
+			diffs = append(diffs, envDiff{Name: name, Left: l})
+			continue
+		}
+		if l.Value != r.Value || l.Source != r.Source {
+			diffs = append(diffs, envDiff{Name: name, Left: l, Right: r})
+		}
+	}
+	for name, r := range rightByName {
+		if _, ok := leftByName[name]; !ok {
+			diffs = append(diffs, envDiff{Name: name, Right: r})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
+
+// printEnvDiff prints differences with '-' for the first pod and '+' for the second
+func printEnvDiff(diffs []envDiff, reveal bool) {
+	if len(diffs) == 0 {
+		fmt.Printf("%sEnvironments are identical%s\n", colorGreen, colorReset)
+		return
+	}
+	for _, d := range diffs {
+		switch {
+		case d.Right == nil:
+			fmt.Printf("%s- %s=%s (%s)%s\n", colorRed, d.Name, d.Left.displayValue(reveal), d.Left.Source, colorReset)
+		case d.Left == nil:
+			fmt.Printf("%s+ %s=%s (%s)%s\n", colorGreen, d.Name, d.Right.displayValue(reveal), d.Right.Source, colorReset)
+		default:
+			note := ""
+			if d.Left.Value != d.Right.Value && (d.Left.Secret || d.Right.Secret) && !reveal {
+				note = " [secret value differs]"
+			}
+			fmt.Printf("%s~ %s%s\n", colorYellow, d.Name, note)
+			fmt.Printf("  - %s (%s)\n", d.Left.displayValue(reveal), d.Left.Source)
+			fmt.Printf("  + %s (%s)%s\n", d.Right.displayValue(reveal), d.Right.Source, colorReset)
+		}
+	}
+	fmt.Printf("\n%d variable(s) differ\n", len(diffs))
+}
@@ -0,0 +1,201 @@
+package podshell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorKind classifies a failure so it can be reported with a suggested fix
+// and mapped to a process exit code
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindAuthExpired
+	KindClusterUnreachable
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindInvalidInput
+	KindTimeout
+)
+
+// exitCancelled is the exit code of an interrupted command, as used by shells
+const exitCancelled = 130
+
+var kindNames = map[ErrorKind]string{
+	KindUnknown:            "unknown",
+	KindAuthExpired:        "auth expired",
+	KindClusterUnreachable: "cluster unreachable",
+	KindForbidden:          "forbidden",
+	KindNotFound:           "not found",
+	KindConflict:           "conflict",
+	KindInvalidInput:       "invalid input",
+	KindTimeout:            "timeout",
+}
+
+// kindHints are the default suggested fixes per kind
+var kindHints = map[ErrorKind]string{
+	KindAuthExpired:        "run gcloud auth login, or check the environment's account and credentials",
+	KindClusterUnreachable: "check your network or VPN, and the environment's proxy and tunnel settings",
+	KindForbidden:          "ask for the missing RBAC role or IAM permission, or check the environment's impersonate setting",
+	KindNotFound:           "check the name and namespace; the resource may have been deleted",
+	KindConflict:           "the resource was changed by someone else; reload it and try again",
+	KindInvalidInput:       "check the value entered, or see --help",
+	KindTimeout:            "try again, or raise --timeout or the environment's timeout",
+}
+
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// ExitCode returns the process exit code used for the kind in non-interactive mode
+func (k ErrorKind) ExitCode() int {
+	switch k {
+	case KindInvalidInput:
+		return 2
+	case KindAuthExpired:
+		return 3
+	case KindClusterUnreachable:
+		return 4
+	case KindForbidden:
+		return 5
+	case KindNotFound:
+		return 6
+	case KindConflict:
+		return 7
+	case KindTimeout:
+		return 8
+	}
+	return 1
+}
+
+// Error wraps an underlying error with its kind and an optional hint that
+// replaces the kind's default one
+type Error struct {
+	Kind ErrorKind
+	Err  error
+	Hint string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// invalidInput reports a value entered by the user or given as a flag that cannot be used
+func invalidInput(format string, args ...interface{}) error {
+	return &Error{Kind: KindInvalidInput, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify returns the kind of an error. Errors of type *Error keep their kind;
+// otherwise API status errors, context errors, network errors and the
+// messages printed by gcloud and kubectl are recognized.
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+
+	switch {
+	case apierrors.IsUnauthorized(err):
+		return KindAuthExpired
+	case apierrors.IsForbidden(err):
+		return KindForbidden
+	case apierrors.IsNotFound(err):
+		return KindNotFound
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return KindConflict
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return KindInvalidInput
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return KindTimeout
+	case apierrors.IsServiceUnavailable(err):
+		return KindClusterUnreachable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return KindTimeout
+		}
+		return KindClusterUnreachable
+	}
+	return classifyMessage(err.Error())
+}
+
+// messageKinds maps fragments of gcloud, kubectl and client-go messages to a
+// kind, for errors that only survive as text. Earlier entries win.
+var messageKinds = []struct {
+	fragment string
+	kind     ErrorKind
+}{
+	{"Reauthentication", KindAuthExpired},
+	{"invalid_grant", KindAuthExpired},
+	{"gcloud auth login", KindAuthExpired},
+	{"You must be logged in", KindAuthExpired},
+	{"Unauthorized", KindAuthExpired},
+	{"token has expired", KindAuthExpired},
+	{"timed out", KindTimeout},
+	{"deadline exceeded", KindTimeout},
+	{"Client.Timeout", KindTimeout},
+	{"Unable to connect to the server", KindClusterUnreachable},
+	{"connection refused", KindClusterUnreachable},
+	{"no such host", KindClusterUnreachable},
+	{"not reachable", KindClusterUnreachable},
+	{"forbidden", KindForbidden},
+	{"Forbidden", KindForbidden},
+	{"PERMISSION_DENIED", KindForbidden},
+	{"(NotFound)", KindNotFound},
+	{"NOT_FOUND", KindNotFound},
+	{"(Conflict)", KindConflict},
+	{"the object has been modified", KindConflict},
+	{"(AlreadyExists)", KindConflict},
+	{"(Invalid)", KindInvalidInput},
+	{"(BadRequest)", KindInvalidInput},
+	{"INVALID_ARGUMENT", KindInvalidInput},
+}
+
+func classifyMessage(msg string) ErrorKind {
+	for _, m := range messageKinds {
+		if strings.Contains(msg, m.fragment) {
+			return m.kind
+		}
+	}
+	return KindUnknown
+}
+
+// Hint returns a suggested fix for an error, or an empty string
+func Hint(err error) string {
+	var typed *Error
+	if errors.As(err, &typed) && typed.Hint != "" {
+		return typed.Hint
+	}
+	return kindHints[Classify(err)]
+}
+
+// ExitCode returns the process exit code for an error in non-interactive mode:
+// 0 for nil, 130 when interrupted and otherwise the code of its kind
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, context.Canceled) {
+		return exitCancelled
+	}
+	return Classify(err).ExitCode()
+}
@@ -1,36 +1,109 @@
 package podshell
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 // Execute handles the main flow of connecting to a GKE cluster and executing commands
-func (a *AccessPods) Execute() {
+func (a *AccessPods) Execute(ctx context.Context) {
 	// Load and select configuration
-	selectedConfig, err := a.setupClusterConfig()
+	selectedConfig, commands, err := a.setupClusterConfig(ctx)
 	if err != nil {
 		a.handleError("Configuration setup failed", err)
-		os.Exit(1)
+		os.Exit(ExitCode(err))
+	}
+	if err := a.registerCustomCommands(selectedConfig, commands); err != nil {
+		a.handleError("Custom commands failed", err)
+		os.Exit(ExitCode(err))
 	}
 
-	// Connect to GKE cluster
-	if err := connectToGKE(selectedConfig); err != nil {
-		a.handleError("GKE connection failed", err)
-		os.Exit(1)
+	// Connect to the cluster, run preflight checks and report them.
+	// Ctrl+C aborts the checks but still stops a started tunnel.
+	connectCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	checks, err := a.connect(connectCtx, selectedConfig)
+	if err == nil {
+		a.checkPermissions(connectCtx, &checks, selectedConfig.namespace)
+	}
+	stop()
+	checks.print()
+	if err != nil {
+		a.handleError("Preflight failed", err)
+		a.close()
+		os.Exit(ExitCode(err))
 	}
+	defer a.close()
 
 	// Start command loop
-	a.commandLoop(selectedConfig)
+	a.commandLoop(ctx, selectedConfig)
+}
+
+// close releases session resources such as a running tunnel
+func (a *AccessPods) close() {
+	if a.tunnel != nil {
+		a.tunnel.close()
+		a.tunnel = nil
+	}
 }
 
-// setupClusterConfig handles configuration loading and selection
-func (a *AccessPods) setupClusterConfig() (ClusterConfig, error) {
+// setupClients creates the Kubernetes and metrics API clients for the session
+func (a *AccessPods) setupClients(config ClusterConfig) error {
+	restConfig, err := loadRestConfig("", "")
+	if err != nil {
+		return err
+	}
+	applyIdentity(restConfig, config)
+	if err := applyProxy(restConfig, config); err != nil {
+		return err
+	}
+	applyRetry(restConfig, config)
+	restConfig.Timeout = config.timeout
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	metrics, err := metricsclient.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	a.config = config
+	a.client = client
+	a.metrics = metrics
+	return nil
+}
+
+// setupClusterConfig handles configuration loading and selection. The custom
+// commands of the configuration are returned along with the selected environment.
+func (a *AccessPods) setupClusterConfig(ctx context.Context) (ClusterConfig, []customCommand, error) {
 	// Read configurations
-	configs, err := readConfigurations(a.FilePath)
+	configs, commands, err := a.configurationsWithCommands()
 	if err != nil {
-		return ClusterConfig{}, err
+		return ClusterConfig{}, nil, err
+	}
+
+	selectedConfig, err := a.selectConfiguration(configs)
+	if err != nil {
+		return ClusterConfig{}, nil, err
+	}
+	if err := a.confirmConfiguration(ctx, selectedConfig); err != nil {
+		return ClusterConfig{}, nil, err
+	}
+
+	return selectedConfig, commands, nil
+}
+
+// selectConfiguration returns the configuration of the environment named by
+// a.Env, or lets the user pick one
+func (a *AccessPods) selectConfiguration(configs []ClusterConfig) (ClusterConfig, error) {
+	if a.Env != "" {
+		return findConfiguration(configs, a.Env)
 	}
 
 	// Display environments
@@ -42,25 +115,32 @@ func (a *AccessPods) setupClusterConfig() (ClusterConfig, error) {
 	// Get user selection
 	choice := a.getUserInput(fmt.Sprintf("Select environment (1-%d): ", len(configs)))
 	if choice < 1 || choice > len(configs) {
-		return ClusterConfig{}, fmt.Errorf("invalid environment selection")
+		return ClusterConfig{}, invalidInput("invalid environment selection")
 	}
-
-	selectedConfig := configs[choice-1]
-	if err := a.confirmConfiguration(selectedConfig); err != nil {
-		return ClusterConfig{}, err
-	}
-
-	return selectedConfig, nil
+	return configs[choice-1], nil
 }
 
 // confirmConfiguration displays and confirms the selected configuration
-func (a *AccessPods) confirmConfiguration(config ClusterConfig) error {
+func (a *AccessPods) confirmConfiguration(ctx context.Context, config ClusterConfig) error {
 	fmt.Printf("\n%sSelected Configuration:%s\n", colorGreen, colorReset)
 	fmt.Printf("Environment: %s\n", config.env)
-	fmt.Printf("Project: %s\n", config.project)
-	fmt.Printf("Cluster: %s\n", config.cluster)
-	fmt.Printf("Zone: %s\n", config.zone)
+	if config.kubeContext != "" {
+		fmt.Printf("Context: %s\n", config.kubeContext)
+	} else {
+		fmt.Printf("Project: %s\n", config.project)
+		fmt.Printf("Cluster: %s\n", config.cluster)
+		fmt.Printf("Zone: %s\n", config.zone)
+	}
 	fmt.Printf("Namespace: %s\n", config.namespace)
+	if config.timeout > 0 {
+		fmt.Printf("Timeout: %v\n", config.timeout)
+	}
+	if config.kubeContext == "" || hasIdentity(config) {
+		fmt.Printf("Identity: %s\n", effectiveIdentity(ctx, config))
+	}
+	if config.protected {
+		fmt.Printf("%sProtected: yes%s\n", colorRed, colorReset)
+	}
 
 	if !a.getUserConfirmation("Continue? (y/n): ") {
 		return fmt.Errorf("operation cancelled by user")
@@ -68,64 +148,83 @@ func (a *AccessPods) confirmConfiguration(config ClusterConfig) error {
 	return nil
 }
 
-func (a *AccessPods) commandLoop(config ClusterConfig) {
+// commandLoop shows the menu generated from the registry, grouped by
+// category, and runs the selected action until the user exits
+func (a *AccessPods) commandLoop(ctx context.Context, config ClusterConfig) {
 	if a.Commands == nil {
 		a.handleError("Command initialization", fmt.Errorf("commands not initialized"))
 		return
 	}
 
-	commandOrder := []CommandType{
-		ShowPods,
-		ConnectPod,
-		ShowLogs,
-		DescribePod,
-		ShowEnv,
-		AdjustCPU,
-		AdjustMemory,
-		ScaleDeployment,
-		PortForward,
-		Exit,
-	}
-
 	for {
+		// Grey out actions the user may not perform in the namespace
+		if err := a.refreshPermissions(ctx, config.namespace); err != nil {
+			a.handleError("Permission check", err)
+		}
+
 		// 顯示命令列表
+		menu := a.Commands.menu()
 		fmt.Printf("\n%sAvailable commands:%s\n", colorYellow, colorReset)
-		for i, cmdType := range commandOrder {
-			cmd, ok := a.Commands[cmdType]
-			if !ok {
-				a.handleError("Command lookup", fmt.Errorf("command %v not registered", cmdType))
+		category := ""
+		for i, action := range menu {
+			if action.Category != category {
+				category = action.Category
+				fmt.Printf("%s%s%s\n", colorYellow, category, colorReset)
+			}
+			if missing, denied := a.unavailable[action.Name]; denied {
+				fmt.Printf("%s%d. %s (needs %s)%s\n", colorGrey, i+1, action.Description, joinPermissions(missing), colorReset)
 				continue
 			}
-			fmt.Printf("%d. %s\n", i+1, cmd.Description)
+			fmt.Printf("%d. %s\n", i+1, action.Description)
 		}
+		exit := len(menu) + 1
+		fmt.Printf("%d. Exit program\n", exit)
 
 		// 獲取用戶輸入
-		choice := a.getUserInput(fmt.Sprintf("\nSelect command (1-%d): ", len(commandOrder)))
-		if choice < 1 || choice > len(commandOrder) {
+		choice := a.getUserInput(fmt.Sprintf("\nSelect command (1-%d): ", exit))
+		if choice == exit {
+			return
+		}
+		if choice < 1 || choice > len(menu) {
 			fmt.Printf("%sInvalid command%s\n", colorRed, colorReset)
 			continue
 		}
 
 		// 執行命令
-		cmdType := commandOrder[choice-1]
-		cmd, ok := a.Commands[cmdType]
-		if !ok {
-			a.handleError("Command execution", fmt.Errorf("command %v not found", cmdType))
+		action := menu[choice-1]
+		if missing, denied := a.unavailable[action.Name]; denied {
+			a.handleError("Command unavailable", fmt.Errorf("missing permissions: %s", joinPermissions(missing)))
 			continue
 		}
-
-		if cmd.Action == nil {
-			a.handleError("Command execution", fmt.Errorf("action not defined for command %v", cmdType))
+		if action.Mutating && !action.RefusesProtected && config.protected &&
+			!a.getUserConfirmation(fmt.Sprintf("%s is protected and %s changes the cluster. Continue? (y/n): ", config.env, action.Name)) {
 			continue
 		}
 
-		if err := cmd.Action(config.namespace); err != nil {
+		// Ctrl+C cancels the running action and returns to the menu
+		actionCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err := action.Handler(actionCtx, a.session(config.namespace))
+		interrupted := actionCtx.Err() != nil && ctx.Err() == nil
+		stop()
+		switch {
+		case interrupted:
+			fmt.Printf("\n%sCancelled%s\n", colorYellow, colorReset)
+		case err != nil:
 			a.handleError("Command execution failed", err)
 		}
+	}
+}
 
-		if cmdType == Exit {
-			return
-		}
+// session describes the connected environment to an action handler
+func (a *AccessPods) session(namespace string) *Session {
+	return &Session{
+		Env:       a.config.env,
+		Namespace: namespace,
+		Protected: a.config.protected,
+		Timeout:   a.config.timeout,
+		Client:    a.client,
+		Metrics:   a.metrics,
+		config:    a.config,
 	}
 }
 
@@ -149,4 +248,7 @@ func (a *AccessPods) getUserConfirmation(prompt string) bool {
 
 func (a *AccessPods) handleError(context string, err error) {
 	fmt.Printf("%sError: %s: %v%s\n", colorRed, context, err, colorReset)
+	if hint := Hint(err); hint != "" {
+		fmt.Printf("%sHint: %s%s\n", colorYellow, hint, colorReset)
+	}
 }
@@ -0,0 +1,342 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FanOutOptions configures a read-only query run against several environments
+type FanOutOptions struct {
+	Query    string   // One of FanOutQueries
+	Workload string   // Deployment or StatefulSet name
+	Vars     []string // Variable names for the env query
+	Envs     []string // Environments to query; empty means all configured
+	Reveal   bool     // Show secret-derived env values
+	Output   string   // Output format: table, wide, json or yaml
+}
+
+// fanOutQuery answers a query for one workload in one environment
+type fanOutQuery func(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef, opts FanOutOptions) ([]fanOutValue, error)
+
+// fanOutQueries maps query names to their implementation
+var fanOutQueries = map[string]fanOutQuery{
+	"images":   queryImages,
+	"pods":     queryPods,
+	"replicas": queryReplicas,
+	"env":      queryEnv,
+}
+
+// FanOutQueries returns the names of the supported fan-out queries
+func FanOutQueries() []string {
+	names := make([]string, 0, len(fanOutQueries))
+	for name := range fanOutQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fanOutValue is one row of a per-environment result
+type fanOutValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// fanOutEnvResult is the result for a single environment; Error is set when the query failed
+type fanOutEnvResult struct {
+	Env       string        `json:"env"`
+	Namespace string        `json:"namespace"`
+	Values    []fanOutValue `json:"values"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// fanOutOutput is the side-by-side comparison across environments
+type fanOutOutput struct {
+	Kind         string            `json:"kind"`
+	Query        string            `json:"query"`
+	Workload     string            `json:"workload"`
+	Environments []fanOutEnvResult `json:"environments"`
+}
+
+// workloadRef identifies the workload being queried
+type workloadRef struct {
+	Kind     string
+	Name     string
+	Replicas int32
+	Ready    int32
+	Selector *metav1.LabelSelector
+	Template corev1.PodTemplateSpec
+}
+
+// FanOut connects to each selected environment concurrently, runs a read-only
+// query against the workload and prints the results side by side. Failures in
+// one environment are reported inline and do not abort the others.
+func (a *AccessPods) FanOut(ctx context.Context, opts FanOutOptions) error {
+	format, err := parseOutputFormat(opts.Output)
+	if err != nil {
+		return err
+	}
+	query, ok := fanOutQueries[opts.Query]
+	if !ok {
+		return invalidInput("unknown query %q (expected one of %s)", opts.Query, strings.Join(FanOutQueries(), ", "))
+	}
+	if opts.Workload == "" {
+		return invalidInput("a workload name is required")
+	}
+	if opts.Query == "env" && len(opts.Vars) == 0 {
+		return invalidInput("the env query requires at least one variable name")
+	}
+
+	configs, err := a.configurations()
+	if err != nil {
+		return err
+	}
+	if len(opts.Envs) > 0 {
+		var selected []ClusterConfig
+		for _, env := range opts.Envs {
+			config, err := findConfiguration(configs, env)
+			if err != nil {
+				return err
+			}
+			selected = append(selected, config)
+		}
+		configs = selected
+	}
+
+	out := &fanOutOutput{
+		Kind:         "FanOutResult",
+		Query:        opts.Query,
+		Workload:     opts.Workload,
+		Environments: make([]fanOutEnvResult, len(configs)),
+	}
+
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		wg.Add(1)
+		go func(i int, config ClusterConfig) {
+			defer wg.Done()
+			result := fanOutEnvResult{Env: config.env, Namespace: config.namespace, Values: []fanOutValue{}}
+			values, err := runFanOutQuery(ctx, config, query, opts)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Values = values
+			}
+			out.Environments[i] = result
+		}(i, config)
+	}
+	wg.Wait()
+
+	return writeOutput(os.Stdout, format, out)
+}
+
+// runFanOutQuery connects to one environment and runs the query there
+func runFanOutQuery(ctx context.Context, config ClusterConfig, query fanOutQuery, opts FanOutOptions) ([]fanOutValue, error) {
+	client, cleanup, err := connectToEnvironment(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	workload, err := findWorkload(ctx, client, config.namespace, opts.Workload)
+	if err != nil {
+		return nil, err
+	}
+	return query(ctx, client, config.namespace, workload, opts)
+}
+
+// findWorkload looks up a Deployment, then a StatefulSet, with the given name
+func findWorkload(ctx context.Context, client kubernetes.Interface, namespace, name string) (*workloadRef, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return &workloadRef{
+			Kind:     "deployment",
+			Name:     name,
+			Replicas: replicasOrDefault(deployment.Spec.Replicas),
+			Ready:    deployment.Status.ReadyReplicas,
+			Selector: deployment.Spec.Selector,
+			Template: deployment.Spec.Template,
+		}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return &workloadRef{
+			Kind:     "statefulset",
+			Name:     name,
+			Replicas: replicasOrDefault(statefulSet.Spec.Replicas),
+			Ready:    statefulSet.Status.ReadyReplicas,
+			Selector: statefulSet.Spec.Selector,
+			Template: statefulSet.Spec.Template,
+		}, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("workload %s not found in namespace %s", name, namespace)
+	}
+	return nil, err
+}
+
+// workloadPods lists the pods selected by the workload
+func workloadPods(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(w.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// queryImages reports the image of each container in the pod template
+func queryImages(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef, opts FanOutOptions) ([]fanOutValue, error) {
+	var values []fanOutValue
+	for _, c := range w.Template.Spec.Containers {
+		values = append(values, fanOutValue{Key: "image/" + c.Name, Value: c.Image})
+	}
+	return values, nil
+}
+
+// queryReplicas reports desired/ready replicas and the HPA range if any
+func queryReplicas(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef, opts FanOutOptions) ([]fanOutValue, error) {
+	values := []fanOutValue{
+		{Key: "desired", Value: fmt.Sprint(w.Replicas)},
+		{Key: "ready", Value: fmt.Sprint(w.Ready)},
+	}
+	kind := "Deployment"
+	if w.Kind == "statefulset" {
+		kind = "StatefulSet"
+	}
+	hpa, err := findHPAForTarget(ctx, client, namespace, kind, w.Name)
+	if err != nil {
+		return nil, err
+	}
+	hpaRange := "-"
+	if hpa != nil {
+		hpaRange = fmt.Sprintf("%d-%d", hpaMinReplicas(hpa), hpa.Spec.MaxReplicas)
+	}
+	return append(values, fanOutValue{Key: "hpa", Value: hpaRange}), nil
+}
+
+// queryPods reports the pods of the workload with their readiness and restarts
+func queryPods(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef, opts FanOutOptions) ([]fanOutValue, error) {
+	pods, err := workloadPods(ctx, client, namespace, w)
+	if err != nil {
+		return nil, err
+	}
+	var ready, restarts int
+	for _, p := range pods {
+		podReady := len(p.Status.ContainerStatuses) > 0
+		for _, cs := range p.Status.ContainerStatuses {
+			podReady = podReady && cs.Ready
+			restarts += int(cs.RestartCount)
+		}
+		if podReady {
+			ready++
+		}
+	}
+	return []fanOutValue{
+		{Key: "pods", Value: fmt.Sprint(len(pods))},
+		{Key: "ready", Value: fmt.Sprint(ready)},
+		{Key: "restarts", Value: fmt.Sprint(restarts)},
+	}, nil
+}
+
+// queryEnv reports the resolved value of the requested variables in the first
+// container of a running pod of the workload
+func queryEnv(ctx context.Context, client kubernetes.Interface, namespace string, w *workloadRef, opts FanOutOptions) ([]fanOutValue, error) {
+	pods, err := workloadPods(ctx, client, namespace, w)
+	if err != nil {
+		return nil, err
+	}
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning {
+			pod = &pods[i]
+			break
+		}
+	}
+	if pod == nil {
+		return nil, fmt.Errorf("no running pods for %s %s", w.Kind, w.Name)
+	}
+
+	vars, err := resolvePodEnv(ctx, client, pod, pod.Spec.Containers[0].Name)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]envVar, len(vars))
+	for _, v := range vars {
+		byName[v.Name] = v
+	}
+
+	var values []fanOutValue
+	for _, name := range opts.Vars {
+		value := "<unset>"
+		if v, ok := byName[name]; ok {
+			value = v.displayValue(opts.Reveal)
+		}
+		values = append(values, fanOutValue{Key: name, Value: value})
+	}
+	return values, nil
+}
+
+func (o *fanOutOutput) writeTable(out io.Writer, wide bool) {
+	// Collect row keys in first-seen order across environments
+	var keys []string
+	seen := map[string]bool{}
+	for _, env := range o.Environments {
+		for _, v := range env.Values {
+			if !seen[v.Key] {
+				seen[v.Key] = true
+				keys = append(keys, v.Key)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := []string{strings.ToUpper(o.Query)}
+	for _, env := range o.Environments {
+		header = append(header, env.Env)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, key := range keys {
+		row := []string{key}
+		for _, env := range o.Environments {
+			value := "-"
+			if env.Error != "" {
+				value = "ERROR"
+			}
+			for _, v := range env.Values {
+				if v.Key == key {
+					value = v.Value
+				}
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	// Report per-environment errors below the table
+	for _, env := range o.Environments {
+		if env.Error != "" {
+			fmt.Fprintf(out, "%s%s: %s%s\n", colorRed, env.Env, env.Error, colorReset)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package podshell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Annotations recording pre-hibernation values so wake can restore them exactly
+const (
+	hibernateReplicasAnnotation    = "go-gcp/hibernated-replicas"
+	hibernateMinReplicasAnnotation = "go-gcp/hibernated-min-replicas"
+)
+
+// hibernateTarget is a workload recorded or restored by hibernate/wake
+type hibernateTarget struct {
+	Kind     string // deployment, statefulset or hpa
+	Name     string
+	Replicas int32 // Current replicas (min replicas for HPAs)
+	Recorded *int32
+}
+
+// hibernateNamespace records the replica counts of every Deployment and StatefulSet
+// (and HPA min replicas) in annotations, then scales the workloads to zero.
+// Protected environments are refused.
+func (a *AccessPods) hibernateNamespace(ctx context.Context, namespace string) error {
+	if a.config.protected {
+		return fmt.Errorf("environment %s is protected; hibernation is only allowed for non-production environments", a.config.env)
+	}
+	targets, err := listHibernateTargets(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no workloads found in namespace %s", namespace)
+	}
+
+	fmt.Printf("\n%sWorkloads to hibernate:%s\n", colorYellow, colorReset)
+	printHibernateTargets(targets)
+
+	// Require the namespace name as confirmation for a namespace-wide change
+	fmt.Printf("\n%sType the namespace name to scale all workloads to zero:%s ", colorRed, colorReset)
+	var confirm string
+	fmt.Scanln(&confirm)
+	if confirm != namespace {
+		return fmt.Errorf("operation cancelled by user")
+	}
+
+	var failed int
+	for _, t := range targets {
+		// Never overwrite a recorded value; a second hibernate must not record zero
+		if t.Recorded != nil {
+			fmt.Printf("%s/%s already hibernated (recorded %d)\n", t.Kind, t.Name, *t.Recorded)
+			continue
+		}
+		if err := hibernateWorkload(ctx, a.client, a.config, namespace, t); err != nil {
+			a.handleError(fmt.Sprintf("Hibernating %s/%s", t.Kind, t.Name), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d workload(s) failed to hibernate", failed)
+	}
+	fmt.Printf("%sNamespace %s hibernated%s\n", colorGreen, namespace, colorReset)
+	return nil
+}
+
+// wakeNamespace restores the replica counts recorded by hibernateNamespace and
+// removes the annotations.
+func (a *AccessPods) wakeNamespace(ctx context.Context, namespace string) error {
+	targets, err := listHibernateTargets(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+	var recorded []hibernateTarget
+	for _, t := range targets {
+		if t.Recorded != nil {
+			recorded = append(recorded, t)
+		}
+	}
+	if len(recorded) == 0 {
+		return fmt.Errorf("no hibernated workloads found in namespace %s", namespace)
+	}
+
+	fmt.Printf("\n%sWorkloads to restore:%s\n", colorYellow, colorReset)
+	printHibernateTargets(recorded)
+	if !a.getUserConfirmation("Restore recorded replicas? (y/n): ") {
+		return fmt.Errorf("operation cancelled by user")
+	}
+
+	var failed int
+	for _, t := range recorded {
+		if err := wakeWorkload(ctx, a.client, a.config, namespace, t); err != nil {
+			a.handleError(fmt.Sprintf("Waking %s/%s", t.Kind, t.Name), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d workload(s) failed to wake", failed)
+	}
+	fmt.Printf("%sNamespace %s restored%s\n", colorGreen, namespace, colorReset)
+	return nil
+}
+
+// listHibernateTargets returns deployments, statefulsets and HPAs with their
+// current replicas and any previously recorded value
+func listHibernateTargets(ctx context.Context, client kubernetes.Interface, namespace string) ([]hibernateTarget, error) {
+	var targets []hibernateTarget
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		t := hibernateTarget{Kind: "deployment", Name: d.Name, Replicas: replicasOrDefault(d.Spec.Replicas)}
+		if t.Recorded, err = recordedValue(d.Annotations, hibernateReplicasAnnotation); err != nil {
+			return nil, fmt.Errorf("deployment %s: %v", d.Name, err)
+		}
+		targets = append(targets, t)
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		t := hibernateTarget{Kind: "statefulset", Name: s.Name, Replicas: replicasOrDefault(s.Spec.Replicas)}
+		if t.Recorded, err = recordedValue(s.Annotations, hibernateReplicasAnnotation); err != nil {
+			return nil, fmt.Errorf("statefulset %s: %v", s.Name, err)
+		}
+		targets = append(targets, t)
+	}
+
+	hpas, err := client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range hpas.Items {
+		h := &hpas.Items[i]
+		t := hibernateTarget{Kind: "hpa", Name: h.Name, Replicas: hpaMinReplicas(h)}
+		if t.Recorded, err = recordedValue(h.Annotations, hibernateMinReplicasAnnotation); err != nil {
+			return nil, fmt.Errorf("hpa %s: %v", h.Name, err)
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// hibernateWorkload records the current value and scales a workload to zero.
+// HPAs are only recorded: they stop acting while their target has zero replicas.
+func hibernateWorkload(ctx context.Context, client kubernetes.Interface, config ClusterConfig, namespace string, t hibernateTarget) error {
+	annotation := hibernateReplicasAnnotation
+	if t.Kind == "hpa" {
+		annotation = hibernateMinReplicasAnnotation
+	}
+	value := strconv.Itoa(int(t.Replicas))
+	if err := annotate(ctx, client, namespace, t.Kind, t.Name, annotation, &value); err != nil {
+		return err
+	}
+	if t.Kind == "hpa" {
+		return nil
+	}
+	return scaleWorkload(ctx, config, t.Kind, t.Name, namespace, 0)
+}
+
+// wakeWorkload restores a recorded value and removes the annotation
+func wakeWorkload(ctx context.Context, client kubernetes.Interface, config ClusterConfig, namespace string, t hibernateTarget) error {
+	if t.Kind == "hpa" {
+		hpa, err := client.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, t.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		updated := hpa.DeepCopy()
+		updated.Spec.MinReplicas = t.Recorded
+		delete(updated.Annotations, hibernateMinReplicasAnnotation)
+		_, err = client.AutoscalingV2().HorizontalPodAutoscalers(namespace).Update(ctx, updated, metav1.UpdateOptions{})
+		return err
+	}
+
+	if err := scaleWorkload(ctx, config, t.Kind, t.Name, namespace, *t.Recorded); err != nil {
+		return err
+	}
+	return annotate(ctx, client, namespace, t.Kind, t.Name, hibernateReplicasAnnotation, nil)
+}
+
+// annotate sets (or removes, when value is nil) an annotation on a deployment, statefulset or HPA
+func annotate(ctx context.Context, client kubernetes.Interface, namespace, kind, name, key string, value *string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]*string{key: value}},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "deployment":
+		_, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "statefulset":
+		_, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "hpa":
+		_, err = client.AutoscalingV2().HorizontalPodAutoscalers(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported kind %s", kind)
+	}
+	return err
+}
+
+// recordedValue parses a replica count annotation, returning nil when absent
+func recordedValue(annotations map[string]string, key string) (*int32, error) {
+	value, ok := annotations[key]
+	if !ok {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s annotation %q", key, value)
+	}
+	replicas := int32(n)
+	return &replicas, nil
+}
+
+// replicasOrDefault returns spec.replicas, which the API defaults to 1 when unset
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// printHibernateTargets prints the workloads with current and recorded replicas
+func printHibernateTargets(targets []hibernateTarget) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tREPLICAS\tRECORDED")
+	for _, t := range targets {
+		recorded := "-"
+		if t.Recorded != nil {
+			recorded = strconv.Itoa(int(*t.Recorded))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", t.Kind, t.Name, t.Replicas, recorded)
+	}
+	w.Flush()
+}
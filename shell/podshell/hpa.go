@@ -0,0 +1,301 @@
+package podshell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// hpaPinAnnotation records the original min/max replicas of a pinned HPA so they can be restored
+const hpaPinAnnotation = "go-gcp/pinned-replicas"
+
+// hpaRange is the min/max replica range stored in the pin annotation
+type hpaRange struct {
+	Min int32 `json:"min"`
+	Max int32 `json:"max"`
+}
+
+// manageHPA lists the HorizontalPodAutoscalers in the namespace and opens the
+// HPA actions menu for the selected one.
+func (a *AccessPods) manageHPA(ctx context.Context, namespace string) error {
+	list, err := a.client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(list.Items) == 0 {
+		return fmt.Errorf("no horizontal pod autoscalers found in namespace %s", namespace)
+	}
+
+	fmt.Printf("\n%sAvailable HPAs:%s\n", colorYellow, colorReset)
+	for i, hpa := range list.Items {
+		fmt.Printf("%d. %s (%s/%s)\n", i+1, hpa.Name, hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+	}
+	choice := a.getUserInput(fmt.Sprintf("Select HPA (1-%d): ", len(list.Items)))
+	if choice < 1 || choice > len(list.Items) {
+		return invalidInput("invalid HPA selection")
+	}
+	return a.hpaActions(ctx, &list.Items[choice-1])
+}
+
+// hpaActions shows the HPA and lets the user inspect, edit, pin or unpin it
+func (a *AccessPods) hpaActions(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	printHPA(hpa)
+
+	_, pinned := hpa.Annotations[hpaPinAnnotation]
+	fmt.Printf("\n%sHPA actions:%s\n", colorYellow, colorReset)
+	fmt.Println("1. Edit min/max replicas")
+	fmt.Println("2. Pin replicas (temporarily set min = max)")
+	if pinned {
+		fmt.Println("3. Unpin replicas (restore recorded min/max)")
+	}
+	fmt.Println("0. Back")
+
+	switch a.getUserInput("Select action: ") {
+	case 0:
+		return nil
+	case 1:
+		minReplicas, err := promptReplicas("Enter new min replicas: ")
+		if err != nil {
+			return err
+		}
+		maxReplicas, err := promptReplicas("Enter new max replicas: ")
+		if err != nil {
+			return err
+		}
+		return setHPARange(ctx, a.client, hpa, minReplicas, maxReplicas)
+	case 2:
+		replicas, err := promptReplicas("Enter replicas to pin: ")
+		if err != nil {
+			return err
+		}
+		return pinHPA(ctx, a.client, hpa, replicas)
+	case 3:
+		if pinned {
+			return unpinHPA(ctx, a.client, hpa)
+		}
+	}
+	return invalidInput("invalid HPA action")
+}
+
+// promptReplicas reads a non-negative replica count from the user
+func promptReplicas(prompt string) (int32, error) {
+	fmt.Printf("\n%s", prompt)
+	var input string
+	fmt.Scanln(&input)
+	replicas, err := strconv.ParseInt(input, 10, 32)
+	if err != nil || replicas < 0 {
+		return 0, invalidInput("invalid replica count: %q", input)
+	}
+	return int32(replicas), nil
+}
+
+// findHPAForTarget returns the HPA scaling the given workload, or nil if there is none
+func findHPAForTarget(ctx context.Context, client kubernetes.Interface, namespace, kind, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	list, err := client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		ref := list.Items[i].Spec.ScaleTargetRef
+		if ref.Kind == kind && ref.Name == name {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// setHPARange updates the min/max replicas of an HPA
+func setHPARange(ctx context.Context, client kubernetes.Interface, hpa *autoscalingv2.HorizontalPodAutoscaler, minReplicas, maxReplicas int32) error {
+	if minReplicas < 1 || maxReplicas < minReplicas {
+		return invalidInput("invalid replica range %d-%d: min must be at least 1 and not exceed max", minReplicas, maxReplicas)
+	}
+	updated := hpa.DeepCopy()
+	updated.Spec.MinReplicas = &minReplicas
+	updated.Spec.MaxReplicas = maxReplicas
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("%sHPA %s now scales between %d and %d replicas%s\n", colorGreen, hpa.Name, minReplicas, maxReplicas, colorReset)
+	return nil
+}
+
+// pinHPA fixes the HPA at the given replica count by setting min and max to it,
+// recording the original range in an annotation for unpinHPA.
+func pinHPA(ctx context.Context, client kubernetes.Interface, hpa *autoscalingv2.HorizontalPodAutoscaler, replicas int32) error {
+	if replicas < 1 {
+		return invalidInput("cannot pin an HPA below 1 replica")
+	}
+	updated := hpa.DeepCopy()
+	if _, ok := updated.Annotations[hpaPinAnnotation]; !ok {
+		original, err := json.Marshal(hpaRange{Min: hpaMinReplicas(hpa), Max: hpa.Spec.MaxReplicas})
+		if err != nil {
+			return err
+		}
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[hpaPinAnnotation] = string(original)
+	}
+	updated.Spec.MinReplicas = &replicas
+	updated.Spec.MaxReplicas = replicas
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("%sHPA %s pinned at %d replicas%s\n", colorGreen, hpa.Name, replicas, colorReset)
+	return nil
+}
+
+// unpinHPA restores the min/max range recorded by pinHPA
+func unpinHPA(ctx context.Context, client kubernetes.Interface, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	value, ok := hpa.Annotations[hpaPinAnnotation]
+	if !ok {
+		return fmt.Errorf("HPA %s is not pinned", hpa.Name)
+	}
+	var original hpaRange
+	if err := json.Unmarshal([]byte(value), &original); err != nil {
+		return fmt.Errorf("invalid %s annotation on HPA %s: %v", hpaPinAnnotation, hpa.Name, err)
+	}
+
+	updated := hpa.DeepCopy()
+	delete(updated.Annotations, hpaPinAnnotation)
+	updated.Spec.MinReplicas = &original.Min
+	updated.Spec.MaxReplicas = original.Max
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("%sHPA %s restored to %d-%d replicas%s\n", colorGreen, hpa.Name, original.Min, original.Max, colorReset)
+	return nil
+}
+
+// hpaMinReplicas returns the effective min replicas (the API defaults it to 1)
+func hpaMinReplicas(hpa *autoscalingv2.HorizontalPodAutoscaler) int32 {
+	if hpa.Spec.MinReplicas != nil {
+		return *hpa.Spec.MinReplicas
+	}
+	return 1
+}
+
+// printHPA prints the replica range, current state and metrics of an HPA
+func printHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	fmt.Printf("\n%sHPA %s:%s\n", colorYellow, hpa.Name, colorReset)
+	fmt.Printf("Target: %s/%s\n", hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+	fmt.Printf("Min/Max replicas: %d/%d\n", hpaMinReplicas(hpa), hpa.Spec.MaxReplicas)
+	fmt.Printf("Current/Desired replicas: %d/%d\n", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)
+	if value, ok := hpa.Annotations[hpaPinAnnotation]; ok {
+		fmt.Printf("%sPinned (original range %s)%s\n", colorYellow, value, colorReset)
+	}
+
+	current := make(map[string]string)
+	for _, m := range hpa.Status.CurrentMetrics {
+		current[metricStatusName(m)] = metricStatusValue(m)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tCURRENT\tTARGET")
+	for _, m := range hpa.Spec.Metrics {
+		name := metricSpecName(m)
+		value, ok := current[name]
+		if !ok {
+			value = "<unknown>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, value, metricTargetValue(metricSpecTarget(m)))
+	}
+	w.Flush()
+}
+
+// metricSpecName returns a display name for an HPA metric spec
+func metricSpecName(m autoscalingv2.MetricSpec) string {
+	switch {
+	case m.Resource != nil:
+		return "resource/" + string(m.Resource.Name)
+	case m.ContainerResource != nil:
+		return fmt.Sprintf("container/%s/%s", m.ContainerResource.Container, m.ContainerResource.Name)
+	case m.Pods != nil:
+		return "pods/" + m.Pods.Metric.Name
+	case m.Object != nil:
+		return "object/" + m.Object.Metric.Name
+	case m.External != nil:
+		return "external/" + m.External.Metric.Name
+	}
+	return string(m.Type)
+}
+
+// metricSpecTarget returns the target of an HPA metric spec
+func metricSpecTarget(m autoscalingv2.MetricSpec) autoscalingv2.MetricTarget {
+	switch {
+	case m.Resource != nil:
+		return m.Resource.Target
+	case m.ContainerResource != nil:
+		return m.ContainerResource.Target
+	case m.Pods != nil:
+		return m.Pods.Target
+	case m.Object != nil:
+		return m.Object.Target
+	case m.External != nil:
+		return m.External.Target
+	}
+	return autoscalingv2.MetricTarget{}
+}
+
+// metricStatusName returns the display name matching metricSpecName for a metric status
+func metricStatusName(m autoscalingv2.MetricStatus) string {
+	switch {
+	case m.Resource != nil:
+		return "resource/" + string(m.Resource.Name)
+	case m.ContainerResource != nil:
+		return fmt.Sprintf("container/%s/%s", m.ContainerResource.Container, m.ContainerResource.Name)
+	case m.Pods != nil:
+		return "pods/" + m.Pods.Metric.Name
+	case m.Object != nil:
+		return "object/" + m.Object.Metric.Name
+	case m.External != nil:
+		return "external/" + m.External.Metric.Name
+	}
+	return string(m.Type)
+}
+
+// metricStatusValue renders the current value of a metric status
+func metricStatusValue(m autoscalingv2.MetricStatus) string {
+	var v autoscalingv2.MetricValueStatus
+	switch {
+	case m.Resource != nil:
+		v = m.Resource.Current
+	case m.ContainerResource != nil:
+		v = m.ContainerResource.Current
+	case m.Pods != nil:
+		v = m.Pods.Current
+	case m.Object != nil:
+		v = m.Object.Current
+	case m.External != nil:
+		v = m.External.Current
+	}
+	switch {
+	case v.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *v.AverageUtilization)
+	case v.AverageValue != nil:
+		return v.AverageValue.String()
+	case v.Value != nil:
+		return v.Value.String()
+	}
+	return "<unknown>"
+}
+
+// metricTargetValue renders a metric target
+func metricTargetValue(t autoscalingv2.MetricTarget) string {
+	switch {
+	case t.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *t.AverageUtilization)
+	case t.AverageValue != nil:
+		return t.AverageValue.String() + " (avg)"
+	case t.Value != nil:
+		return t.Value.String()
+	}
+	return "-"
+}
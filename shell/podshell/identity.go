@@ -0,0 +1,118 @@
+package podshell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// identityEnv returns the gcloud property overrides for the environment's
+// identity. gcloud, kubectl and the GKE auth plugin all honour them, so they
+// apply without changing the user's gcloud configuration. Only the settings
+// the environment makes are returned; otherwise the user's own gcloud
+// defaults, including overrides exported in their shell, stay in effect.
+// They are passed to each command rather than exported, as one process may
+// serve several environments.
+func identityEnv(config ClusterConfig) []string {
+	var env []string
+	if config.account != "" {
+		env = append(env, "CLOUDSDK_CORE_ACCOUNT="+config.account)
+	}
+	if config.impersonate != "" {
+		env = append(env, "CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT="+config.impersonate)
+	}
+	if config.credentialsFile != "" {
+		env = append(env, "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE="+config.credentialsFile)
+	}
+	return env
+}
+
+// hasIdentity reports whether the environment sets its own gcloud identity
+func hasIdentity(config ClusterConfig) bool {
+	return config.account != "" || config.impersonate != "" || config.credentialsFile != ""
+}
+
+// gcloudCommand builds a gcloud command running as the environment's identity.
+// The command is killed when ctx ends.
+func gcloudCommand(ctx context.Context, config ClusterConfig, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "gcloud", args...)
+	cmd.Env = append(os.Environ(), identityEnv(config)...)
+	return cmd
+}
+
+// kubectlCommand builds a kubectl command for the environment, whose auth
+// plugin runs as the environment's identity. Environments with a kubeconfig
+// context select it per command. The command is killed when ctx ends.
+func kubectlCommand(ctx context.Context, config ClusterConfig, args ...string) *exec.Cmd {
+	if config.kubeContext != "" {
+		args = append([]string{"--context", config.kubeContext}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Env = commandEnv(config)
+	return cmd
+}
+
+// commandEnv returns the process environment for kubectl and custom commands
+// run for the environment. They reach a private cluster through its proxy;
+// gcloud does not, so it keeps the environment of gcloudCommand.
+func commandEnv(config ClusterConfig) []string {
+	env := append(os.Environ(), identityEnv(config)...)
+	if config.proxy != "" {
+		env = append(env, "HTTPS_PROXY="+config.proxy)
+	}
+	return env
+}
+
+// applyIdentity passes the identity overrides to the kubeconfig exec auth
+// plugin of the environment's API clients
+func applyIdentity(restConfig *rest.Config, config ClusterConfig) {
+	if restConfig.ExecProvider == nil {
+		return
+	}
+	for _, kv := range identityEnv(config) {
+		key, value, _ := strings.Cut(kv, "=")
+		restConfig.ExecProvider.Env = append(restConfig.ExecProvider.Env, clientcmdapi.ExecEnvVar{Name: key, Value: value})
+	}
+}
+
+// effectiveIdentity describes who API calls will be made as
+func effectiveIdentity(ctx context.Context, config ClusterConfig) string {
+	identity := config.account
+	switch {
+	case identity != "":
+	case config.credentialsFile != "":
+		identity = credentialsEmail(config.credentialsFile)
+	default:
+		ctx, cancel := withTimeout(ctx, config.timeout)
+		defer cancel()
+		out, err := gcloudCommand(ctx, config, "config", "get-value", "account").Output()
+		identity = strings.TrimSpace(string(out))
+		if err != nil || identity == "" {
+			identity = "unknown (no active gcloud account)"
+		}
+	}
+
+	if config.impersonate != "" {
+		return fmt.Sprintf("%s (impersonated by %s)", config.impersonate, identity)
+	}
+	return identity
+}
+
+// credentialsEmail returns the service account email of a key file, or
+// describes the file when it has none (e.g. user credentials)
+func credentialsEmail(path string) string {
+	var key struct {
+		ClientEmail string `json:"client_email"`
+	}
+	data, err := os.ReadFile(path)
+	if err == nil && json.Unmarshal(data, &key) == nil && key.ClientEmail != "" {
+		return key.ClientEmail
+	}
+	return "credentials file " + path
+}
@@ -0,0 +1,117 @@
+package podshell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ImportOptions configures config import-kubeconfig
+type ImportOptions struct {
+	Kubeconfig string   // Kubeconfig to read; empty uses $KUBECONFIG or ~/.kube/config
+	Path       string   // Configuration file to write or merge into; empty prints to stdout
+	Contexts   []string // Contexts to import; empty imports all
+	UseContext bool     // Connect through the kube context instead of gcloud for GKE contexts too
+}
+
+// ImportKubeconfig turns kubeconfig contexts into environments. GKE contexts
+// named gke_<project>_<location>_<cluster> keep their GCP coordinates; other
+// contexts are always used directly through the context.
+func ImportKubeconfig(opts ImportOptions) error {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if opts.Kubeconfig != "" {
+		rules.ExplicitPath = opts.Kubeconfig
+	}
+	kubeconfig, err := rules.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	names := opts.Contexts
+	if len(names) == 0 {
+		for name := range kubeconfig.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no contexts found in kubeconfig")
+	}
+
+	var imported []ClusterConfig
+	used := map[string]bool{}
+	for _, name := range names {
+		kubeContext, ok := kubeconfig.Contexts[name]
+		if !ok {
+			return fmt.Errorf("context %s not found in kubeconfig", name)
+		}
+
+		config := ClusterConfig{env: name, namespace: kubeContext.Namespace}
+		if project, location, cluster, ok := parseGKEContextName(name); ok {
+			config.env, config.project, config.zone, config.cluster = cluster, project, location, cluster
+			if opts.UseContext {
+				config.kubeContext = name
+			}
+		} else {
+			config.kubeContext = name
+		}
+		// Same-named clusters in different projects fall back to the context name
+		if used[config.env] {
+			config.env = name
+		}
+		used[config.env] = true
+		if config.namespace == "" {
+			config.namespace = "default"
+		}
+		config.protected = isProductionEnv(config.env)
+		imported = append(imported, config)
+	}
+
+	if opts.Path == "" {
+		return writeStructuredConfigTo(os.Stdout, imported, nil)
+	}
+	return mergeIntoConfigFile(opts.Path, imported)
+}
+
+// parseGKEContextName splits a context name created by gcloud get-credentials
+func parseGKEContextName(name string) (project, location, cluster string, ok bool) {
+	parts := strings.SplitN(name, "_", 4)
+	if len(parts) != 4 || parts[0] != "gke" || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], parts[3], true
+}
+
+// mergeIntoConfigFile adds configs to a structured configuration file,
+// replacing environments with the same name and keeping its custom commands;
+// the file is created if missing
+func mergeIntoConfigFile(path string, configs []ClusterConfig) error {
+	if err := checkStructuredPath(path); err != nil {
+		return err
+	}
+	var commands []customCommand
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err == nil {
+		if !isStructuredConfig(path, data) {
+			return fmt.Errorf("%s uses the line format; import into a .yaml file instead", path)
+		}
+		existing, existingCommands, err := readConfigurations(path)
+		if err != nil {
+			return err
+		}
+		commands = existingCommands
+		configs = mergeConfigurations(existing, configs)
+	}
+
+	if err := writeStructuredConfig(path, configs, commands); err != nil {
+		return err
+	}
+	fmt.Printf("%sWrote %d environment(s) to %s%s\n", colorGreen, len(configs), path, colorReset)
+	return nil
+}
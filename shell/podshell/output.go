@@ -0,0 +1,67 @@
+package podshell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// outputFormat selects how non-interactive results are written
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputWide  outputFormat = "wide"
+	outputJSON  outputFormat = "json"
+	outputYAML  outputFormat = "yaml"
+)
+
+// OutputFormats lists the accepted values of the --output flag
+var OutputFormats = []string{string(outputTable), string(outputWide), string(outputJSON), string(outputYAML)}
+
+// tableWriter is implemented by every non-interactive result so it can be
+// rendered as a table; the same value is marshalled for json and yaml output.
+type tableWriter interface {
+	writeTable(w io.Writer, wide bool)
+}
+
+// parseOutputFormat validates an --output flag value; empty means table
+func parseOutputFormat(value string) (outputFormat, error) {
+	if value == "" {
+		return outputTable, nil
+	}
+	for _, f := range OutputFormats {
+		if strings.EqualFold(value, f) {
+			return outputFormat(f), nil
+		}
+	}
+	return "", invalidInput("unsupported output format %q (expected one of %s)", value, strings.Join(OutputFormats, "|"))
+}
+
+// writeOutput renders a result in the requested format
+func writeOutput(w io.Writer, format outputFormat, result tableWriter) error {
+	switch format {
+	case outputJSON:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case outputYAML:
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case outputWide:
+		result.writeTable(w, true)
+	default:
+		result.writeTable(w, false)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Limits for the compact overview sections
+const (
+	maxRestartHotSpots = 5
+	maxOverviewEvents  = 8
+)
+
+// namespaceOverview is a one-screen summary of a namespace
+type namespaceOverview struct {
+	Namespace string
+	Workloads []workloadSummary
+	PodPhases map[corev1.PodPhase]int
+	HotSpots  []restartHotSpot
+	Services  []serviceSummary
+	Ingresses []ingressSummary
+	Claims    []claimSummary
+	Warnings  []corev1.Event
+	TotalPods int
+}
+
+type workloadSummary struct {
+	Kind    string
+	Name    string
+	Desired int32
+	Ready   int32
+}
+
+type restartHotSpot struct {
+	Pod       string
+	Container string
+	Restarts  int32
+	Reason    string // Last termination reason
+}
+
+type serviceSummary struct {
+	Name      string
+	Type      corev1.ServiceType
+	Endpoints int // Ready endpoint addresses
+	NotReady  int
+}
+
+type ingressSummary struct {
+	Name    string
+	Hosts   []string
+	Address string
+}
+
+type claimSummary struct {
+	Name         string
+	Phase        corev1.PersistentVolumeClaimPhase
+	Capacity     string
+	StorageClass string
+}
+
+// namespaceOverviewAction prints workloads, pods, services, ingresses, PVCs and
+// recent warnings of the namespace in one compact screen.
+func (a *AccessPods) namespaceOverviewAction(ctx context.Context, namespace string) error {
+	overview, err := gatherOverview(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+	printOverview(overview)
+	return nil
+}
+
+// listScalableWorkloads returns the Deployments and StatefulSets of the namespace
+func listScalableWorkloads(ctx context.Context, client kubernetes.Interface, namespace string) ([]workloadSummary, error) {
+	var workloads []workloadSummary
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		workloads = append(workloads, workloadSummary{"deployment", d.Name, replicasOrDefault(d.Spec.Replicas), d.Status.ReadyReplicas})
+	}
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		workloads = append(workloads, workloadSummary{"statefulset", s.Name, replicasOrDefault(s.Spec.Replicas), s.Status.ReadyReplicas})
+	}
+	return workloads, nil
+}
+
+// gatherOverview collects the namespace summary from the API
+func gatherOverview(ctx context.Context, client kubernetes.Interface, namespace string) (*namespaceOverview, error) {
+	o := &namespaceOverview{Namespace: namespace, PodPhases: map[corev1.PodPhase]int{}}
+	opts := metav1.ListOptions{}
+
+	// Workloads
+	workloads, err := listScalableWorkloads(ctx, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	o.Workloads = workloads
+	daemonSets, err := client.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets.Items {
+		o.Workloads = append(o.Workloads, workloadSummary{"daemonset", d.Name, d.Status.DesiredNumberScheduled, d.Status.NumberReady})
+	}
+
+	// Pods by phase and restart hot spots
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	o.TotalPods = len(pods.Items)
+	for _, p := range pods.Items {
+		o.PodPhases[p.Status.Phase]++
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.RestartCount == 0 {
+				continue
+			}
+			spot := restartHotSpot{Pod: p.Name, Container: cs.Name, Restarts: cs.RestartCount}
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				spot.Reason = t.Reason
+			}
+			o.HotSpots = append(o.HotSpots, spot)
+		}
+	}
+	sort.Slice(o.HotSpots, func(i, j int) bool { return o.HotSpots[i].Restarts > o.HotSpots[j].Restarts })
+	if len(o.HotSpots) > maxRestartHotSpots {
+		o.HotSpots = o.HotSpots[:maxRestartHotSpots]
+	}
+
+	// Services with endpoint counts
+	services, err := client.CoreV1().Services(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := client.CoreV1().Endpoints(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	endpointsByName := make(map[string]corev1.Endpoints, len(endpoints.Items))
+	for _, ep := range endpoints.Items {
+		endpointsByName[ep.Name] = ep
+	}
+	for _, svc := range services.Items {
+		summary := serviceSummary{Name: svc.Name, Type: svc.Spec.Type}
+		for _, subset := range endpointsByName[svc.Name].Subsets {
+			summary.Endpoints += len(subset.Addresses)
+			summary.NotReady += len(subset.NotReadyAddresses)
+		}
+		o.Services = append(o.Services, summary)
+	}
+
+	// Ingresses
+	ingresses, err := client.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ingresses.Items {
+		summary := ingressSummary{Name: ing.Name}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host != "" {
+				summary.Hosts = append(summary.Hosts, rule.Host)
+			}
+		}
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			summary.Address = lb.IP
+			if summary.Address == "" {
+				summary.Address = lb.Hostname
+			}
+		}
+		o.Ingresses = append(o.Ingresses, summary)
+	}
+
+	// Persistent volume claims
+	claims, err := client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range claims.Items {
+		summary := claimSummary{Name: pvc.Name, Phase: pvc.Status.Phase}
+		if q, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			summary.Capacity = q.String()
+		}
+		if pvc.Spec.StorageClassName != nil {
+			summary.StorageClass = *pvc.Spec.StorageClassName
+		}
+		o.Claims = append(o.Claims, summary)
+	}
+
+	// Recent warning events
+	events, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return nil, err
+	}
+	o.Warnings = events.Items
+	sort.Slice(o.Warnings, func(i, j int) bool { return eventTime(o.Warnings[i]).After(eventTime(o.Warnings[j])) })
+	if len(o.Warnings) > maxOverviewEvents {
+		o.Warnings = o.Warnings[:maxOverviewEvents]
+	}
+	return o, nil
+}
+
+// printOverview renders the namespace overview as compact sections
+func printOverview(o *namespaceOverview) {
+	fmt.Printf("\n%sNamespace %s%s\n", colorYellow, o.Namespace, colorReset)
+
+	section := func(title string, count int) bool {
+		fmt.Printf("\n%s%s (%d)%s\n", colorYellow, title, count, colorReset)
+		return count > 0
+	}
+
+	if section("Workloads", len(o.Workloads)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAME\tREADY")
+		for _, wl := range o.Workloads {
+			ready := fmt.Sprintf("%d/%d", wl.Ready, wl.Desired)
+			if wl.Ready < wl.Desired {
+				ready = colorRed + ready + colorReset
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", wl.Kind, wl.Name, ready)
+		}
+		w.Flush()
+	}
+
+	if section("Pods", o.TotalPods) {
+		var phases []string
+		for _, phase := range []corev1.PodPhase{corev1.PodRunning, corev1.PodPending, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown} {
+			if n := o.PodPhases[phase]; n > 0 {
+				phases = append(phases, fmt.Sprintf("%s=%d", phase, n))
+			}
+		}
+		fmt.Println(strings.Join(phases, "  "))
+	}
+
+	if section("Restart hot spots", len(o.HotSpots)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, h := range o.HotSpots {
+			fmt.Fprintf(w, "%s/%s\t%d restarts\t%s\n", h.Pod, h.Container, h.Restarts, h.Reason)
+		}
+		w.Flush()
+	}
+
+	if section("Services", len(o.Services)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tENDPOINTS")
+		for _, s := range o.Services {
+			eps := fmt.Sprintf("%d", s.Endpoints)
+			if s.NotReady > 0 {
+				eps += fmt.Sprintf(" (+%d not ready)", s.NotReady)
+			}
+			if s.Endpoints == 0 && s.Type != corev1.ServiceTypeExternalName {
+				eps = colorRed + eps + colorReset
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Type, eps)
+		}
+		w.Flush()
+	}
+
+	if section("Ingresses", len(o.Ingresses)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, i := range o.Ingresses {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", i.Name, strings.Join(i.Hosts, ","), i.Address)
+		}
+		w.Flush()
+	}
+
+	if section("PVCs", len(o.Claims)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, c := range o.Claims {
+			phase := string(c.Phase)
+			if c.Phase != corev1.ClaimBound {
+				phase = colorRed + phase + colorReset
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.Capacity, c.StorageClass, phase)
+		}
+		w.Flush()
+	}
+
+	if section("Recent warnings", len(o.Warnings)) {
+		for _, ev := range o.Warnings {
+			fmt.Printf("%s%s %s/%s %s: %s%s\n", colorRed, eventTime(ev).Format(time.TimeOnly),
+				strings.ToLower(ev.InvolvedObject.Kind), ev.InvolvedObject.Name, ev.Reason, ev.Message, colorReset)
+		}
+	}
+}
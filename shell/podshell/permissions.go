@@ -0,0 +1,192 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is a namespaced RBAC verb on a resource needed by an action
+type Permission struct {
+	Verb        string
+	Group       string
+	Resource    string
+	Subresource string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	return p.Verb + " " + resource
+}
+
+// Permissions shared by several actions
+var (
+	permListPods      = Permission{Verb: "list", Resource: "pods"}
+	permGetPods       = Permission{Verb: "get", Resource: "pods"}
+	permPatchPods     = Permission{Verb: "patch", Resource: "pods"}
+	permListEvents    = Permission{Verb: "list", Resource: "events"}
+	permListPodUsage  = Permission{Verb: "list", Group: "metrics.k8s.io", Resource: "pods"}
+	permGetConfigMaps = Permission{Verb: "get", Resource: "configmaps"}
+	permGetSecrets    = Permission{Verb: "get", Resource: "secrets"}
+	permListHPAs      = Permission{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"}
+	permUpdateHPAs    = Permission{Verb: "update", Group: "autoscaling", Resource: "horizontalpodautoscalers"}
+	permListDeploys   = Permission{Verb: "list", Group: "apps", Resource: "deployments"}
+	permListStateful  = Permission{Verb: "list", Group: "apps", Resource: "statefulsets"}
+)
+
+// joinPermissions formats permissions as a comma-separated list
+func joinPermissions(perms []Permission) string {
+	names := make([]string, len(perms))
+	for i, p := range perms {
+		names[i] = p.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// missingPermissions returns the permissions the current user lacks in the
+// namespace. A SelfSubjectRulesReview answers most of them in one call; when
+// its rules are incomplete (GKE also authorizes through IAM, for example) the
+// permissions the rules do not grant are confirmed with a
+// SelfSubjectAccessReview each.
+func missingPermissions(ctx context.Context, client kubernetes.Interface, namespace string, perms []Permission) ([]Permission, error) {
+	rulesReview := &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}
+	var rules []authorizationv1.ResourceRule
+	incomplete := true
+	if result, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, rulesReview, metav1.CreateOptions{}); err == nil {
+		rules, incomplete = result.Status.ResourceRules, result.Status.Incomplete
+	}
+
+	var missing []Permission
+	for _, p := range perms {
+		if rulesAllow(rules, p) {
+			continue
+		}
+		if !incomplete {
+			missing = append(missing, p)
+			continue
+		}
+
+		allowed, err := accessReview(ctx, client, namespace, p)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// accessReview asks whether the current user holds a single permission
+func accessReview(ctx context.Context, client kubernetes.Interface, namespace string, p Permission) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        p.Verb,
+				Group:       p.Group,
+				Resource:    p.Resource,
+				Subresource: p.Subresource,
+			},
+		},
+	}
+	result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("access review for %s failed: %v", p, err)
+	}
+	return result.Status.Allowed, nil
+}
+
+// rulesAllow reports whether any rule grants the permission, matching
+// wildcards the way RBAC does. Rules limited to resource names are ignored.
+func rulesAllow(rules []authorizationv1.ResourceRule, p Permission) bool {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	for _, rule := range rules {
+		if len(rule.ResourceNames) > 0 {
+			continue
+		}
+		if !containsOrWildcard(rule.Verbs, p.Verb) || !containsOrWildcard(rule.APIGroups, p.Group) {
+			continue
+		}
+		for _, r := range rule.Resources {
+			if r == "*" || r == resource || (p.Subresource != "" && r == "*/"+p.Subresource) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsOrWildcard reports whether values holds value or "*"
+func containsOrWildcard(values []string, value string) bool {
+	for _, v := range values {
+		if v == value || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// unavailableCommands checks every registered action's permissions, reviewing
+// each distinct permission once, and returns the missing ones per action name
+func (a *AccessPods) unavailableCommands(ctx context.Context, namespace string) (map[string][]Permission, error) {
+	actions := a.Commands.Actions()
+	var unique []Permission
+	seen := map[Permission]bool{}
+	for _, action := range actions {
+		for _, p := range action.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				unique = append(unique, p)
+			}
+		}
+	}
+
+	missing, err := missingPermissions(ctx, a.client, namespace, unique)
+	if err != nil {
+		return nil, err
+	}
+	denied := map[Permission]bool{}
+	for _, p := range missing {
+		denied[p] = true
+	}
+
+	unavailable := map[string][]Permission{}
+	for _, action := range actions {
+		for _, p := range action.Permissions {
+			if denied[p] {
+				unavailable[action.Name] = append(unavailable[action.Name], p)
+			}
+		}
+	}
+	return unavailable, nil
+}
+
+// refreshPermissions computes the unavailable actions once per namespace so
+// the menu can grey them out. If the check fails every action stays enabled.
+func (a *AccessPods) refreshPermissions(ctx context.Context, namespace string) error {
+	if a.unavailable != nil && a.permissionsNamespace == namespace {
+		return nil
+	}
+	unavailable, err := a.unavailableCommands(ctx, namespace)
+	if err != nil {
+		unavailable = map[string][]Permission{}
+	}
+	a.unavailable, a.permissionsNamespace = unavailable, namespace
+	return err
+}
@@ -0,0 +1,189 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkStatus is the outcome of a preflight check
+type checkStatus int
+
+const (
+	checkPassed checkStatus = iota
+	checkWarning
+	checkFailed
+)
+
+// preflightCheck is one line of the connection checklist
+type preflightCheck struct {
+	Name   string
+	Status checkStatus
+	Detail string
+}
+
+// checklist collects preflight results in order
+type checklist []preflightCheck
+
+func (c *checklist) pass(name, detail string) {
+	*c = append(*c, preflightCheck{name, checkPassed, detail})
+}
+
+func (c *checklist) warn(name, detail string) {
+	*c = append(*c, preflightCheck{name, checkWarning, detail})
+}
+
+// fail records a failed check and returns it as an error
+func (c *checklist) fail(name string, err error) error {
+	*c = append(*c, preflightCheck{name, checkFailed, err.Error()})
+	return fmt.Errorf("%s: %w", strings.ToLower(name), err)
+}
+
+// print renders the checklist with a colored mark per check
+func (c checklist) print() {
+	fmt.Printf("\n%sPreflight checks:%s\n", colorYellow, colorReset)
+	for _, check := range c {
+		mark := colorGreen + "✔" + colorReset
+		switch check.Status {
+		case checkWarning:
+			mark = colorYellow + "!" + colorReset
+		case checkFailed:
+			mark = colorRed + "✘" + colorReset
+		}
+		fmt.Printf(" %s %-20s %s\n", mark, check.Name, check.Detail)
+	}
+}
+
+// requiredBinaries lists the executables an environment needs on PATH
+func requiredBinaries(config ClusterConfig) []string {
+	binaries := []string{"kubectl"}
+	if config.kubeContext == "" {
+		binaries = append(binaries, "gcloud", "gke-gcloud-auth-plugin")
+	} else if hasIdentity(config) {
+		binaries = append(binaries, "gcloud")
+	}
+	return binaries
+}
+
+// connect prepares the session for an environment: required binaries, gcloud
+// identity, cluster credentials, proxy or tunnel, API clients, API server
+// version and namespace. Checks stop at the first failure.
+func (a *AccessPods) connect(ctx context.Context, config ClusterConfig) (checklist, error) {
+	var checks checklist
+
+	// Required binaries
+	var missing []string
+	for _, binary := range requiredBinaries(config) {
+		if _, err := exec.LookPath(binary); err != nil {
+			missing = append(missing, binary)
+		}
+	}
+	if len(missing) > 0 {
+		return checks, checks.fail("Binaries", &Error{
+			Err:  fmt.Errorf("not found on PATH: %s", strings.Join(missing, ", ")),
+			Hint: "install the Google Cloud SDK with kubectl and the gke-gcloud-auth-plugin component",
+		})
+	}
+	checks.pass("Binaries", strings.Join(requiredBinaries(config), ", "))
+
+	// gcloud identity
+	if config.kubeContext == "" || hasIdentity(config) {
+		if err := checkAccessToken(ctx, config); err != nil {
+			return checks, checks.fail("gcloud auth", err)
+		}
+		checks.pass("gcloud auth", effectiveIdentity(ctx, config))
+	}
+
+	// Cluster credentials
+	if err := connectToCluster(ctx, config); err != nil {
+		return checks, checks.fail("Cluster credentials", err)
+	}
+	if config.kubeContext != "" {
+		checks.pass("Cluster credentials", "context "+config.kubeContext)
+	} else {
+		checks.pass("Cluster credentials", fmt.Sprintf("%s/%s/%s", config.project, config.zone, config.cluster))
+	}
+
+	// Private clusters: start the tunnel serving the proxy
+	if config.tunnel != "" {
+		t, err := startTunnel(ctx, config)
+		if err != nil {
+			return checks, checks.fail("Tunnel", err)
+		}
+		a.tunnel = t
+		checks.pass("Tunnel", "listening on "+t.address)
+	}
+
+	// API clients and server reachability
+	if err := a.setupClients(config); err != nil {
+		return checks, checks.fail("API clients", err)
+	}
+	version, err := checkAPIServer(ctx, a.client)
+	if err != nil {
+		return checks, checks.fail("API server", err)
+	}
+	checks.pass("API server", version)
+
+	// Namespace; namespace-scoped users may not be allowed to read it
+	_, err = a.client.CoreV1().Namespaces().Get(ctx, config.namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		checks.pass("Namespace", config.namespace)
+	case apierrors.IsForbidden(err):
+		checks.warn("Namespace", fmt.Sprintf("%s (not allowed to verify it exists)", config.namespace))
+	case apierrors.IsNotFound(err):
+		return checks, checks.fail("Namespace", &Error{
+			Kind: KindNotFound,
+			Err:  fmt.Errorf("%s does not exist", config.namespace),
+			Hint: "check the environment's namespace in the configuration",
+		})
+	default:
+		return checks, checks.fail("Namespace", err)
+	}
+	return checks, nil
+}
+
+// checkAccessToken verifies gcloud can issue an access token for the
+// environment's identity, retrying token refresh races
+func checkAccessToken(ctx context.Context, config ClusterConfig) error {
+	return config.retryPolicy().do(ctx, "print-access-token", func() error {
+		authCtx, cancel := withTimeout(ctx, config.timeout)
+		defer cancel()
+		out, err := gcloudCommand(authCtx, config, "auth", "print-access-token").CombinedOutput()
+		switch {
+		case err == nil:
+			return nil
+		case authCtx.Err() != nil:
+			return operationError(authCtx, config.timeout, err)
+		case isTransient(commandError(err, out)):
+			return commandError(err, out)
+		}
+		// Anything else means the login is unusable
+		return &Error{Kind: KindAuthExpired, Err: commandError(err, out)}
+	})
+}
+
+// checkPermissions records which menu actions the user cannot perform
+func (a *AccessPods) checkPermissions(ctx context.Context, checks *checklist, namespace string) {
+	if err := a.refreshPermissions(ctx, namespace); err != nil {
+		checks.warn("Permissions", err.Error())
+		return
+	}
+	if len(a.unavailable) == 0 {
+		checks.pass("Permissions", "all actions permitted")
+		return
+	}
+	checks.warn("Permissions", fmt.Sprintf("%d action(s) unavailable", len(a.unavailable)))
+}
+
+// commandError combines a command's error with what it printed
+func commandError(err error, output []byte) error {
+	if msg := strings.TrimSpace(string(output)); msg != "" {
+		return fmt.Errorf("%v: %s", err, msg)
+	}
+	return err
+}
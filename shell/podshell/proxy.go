@@ -0,0 +1,235 @@
+package podshell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Tunnel supervision and health check limits
+const (
+	tunnelStartTimeout = 30 * time.Second
+	maxTunnelRestarts  = 5
+	apiHealthTimeout   = 15 * time.Second
+)
+
+// parseProxyURL validates a proxy setting; http, https and socks5 are supported
+func parseProxyURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, invalidInput("invalid proxy URL %q: %v", value, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, invalidInput("invalid proxy URL %q: scheme must be http, https or socks5", value)
+	}
+	if u.Port() == "" {
+		return nil, invalidInput("invalid proxy URL %q: a port is required", value)
+	}
+	return u, nil
+}
+
+// applyProxy routes the client's API traffic through the environment's proxy
+func applyProxy(restConfig *rest.Config, config ClusterConfig) error {
+	if config.proxy == "" {
+		return nil
+	}
+	u, err := parseProxyURL(config.proxy)
+	if err != nil {
+		return err
+	}
+	restConfig.Proxy = http.ProxyURL(u)
+	return nil
+}
+
+// tunnel is a supervised local process, such as an IAP tunnel to a bastion,
+// that serves the environment's proxy. It is restarted if it exits.
+type tunnel struct {
+	env     string
+	args    []string
+	envVars []string
+	address string // host:port the tunnel listens on
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	output  *syncBuffer // Combined output of the current process
+	stopped bool
+	done    chan struct{}
+}
+
+// startTunnel launches the environment's tunnel command and waits until its
+// proxy port accepts connections. ctx only bounds the wait; the tunnel runs
+// until closed.
+func startTunnel(ctx context.Context, config ClusterConfig) (*tunnel, error) {
+	u, err := parseProxyURL(config.proxy)
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Fields(config.tunnel)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty tunnel command")
+	}
+
+	t := &tunnel{
+		env:     config.env,
+		args:    args,
+		envVars: identityEnv(config),
+		address: u.Host,
+		done:    make(chan struct{}),
+	}
+	fmt.Fprintf(os.Stderr, "Starting tunnel for %s: %s\n", config.env, config.tunnel)
+	if err := t.launch(); err != nil {
+		return nil, err
+	}
+	go t.supervise()
+
+	if err := waitForPort(ctx, t.address, tunnelStartTimeout); err != nil {
+		output := t.lastOutput()
+		t.close()
+		return nil, &Error{
+			Kind: KindClusterUnreachable,
+			Err:  fmt.Errorf("tunnel for %s did not become ready: %v\n%s", config.env, err, output),
+			Hint: "check the environment's tunnel command and that its proxy port matches",
+		}
+	}
+	return t, nil
+}
+
+// launch starts the tunnel process
+func (t *tunnel) launch() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return nil
+	}
+
+	cmd := exec.Command(t.args[0], t.args[1:]...)
+	cmd.Env = append(os.Environ(), t.envVars...)
+	output := &syncBuffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start tunnel for %s: %v", t.env, err)
+	}
+	t.cmd, t.output = cmd, output
+	return nil
+}
+
+// supervise restarts the tunnel when it exits unexpectedly
+func (t *tunnel) supervise() {
+	defer close(t.done)
+	for restarts := 0; ; restarts++ {
+		t.mu.Lock()
+		cmd := t.cmd
+		t.mu.Unlock()
+		err := cmd.Wait()
+
+		t.mu.Lock()
+		stopped := t.stopped
+		t.mu.Unlock()
+		if stopped {
+			return
+		}
+		if restarts == maxTunnelRestarts {
+			fmt.Fprintf(os.Stderr, "\n%sTunnel for %s exited (%v), giving up after %d restarts%s\n", colorRed, t.env, err, restarts, colorReset)
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "\n%sTunnel for %s exited (%v), restarting%s\n", colorYellow, t.env, err, colorReset)
+		time.Sleep(time.Duration(restarts+1) * time.Second)
+		if err := t.launch(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s%v%s\n", colorRed, err, colorReset)
+			return
+		}
+	}
+}
+
+// lastOutput returns what the current tunnel process has printed
+func (t *tunnel) lastOutput() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(t.output.String())
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// close stops the tunnel and waits for the supervisor to finish
+func (t *tunnel) close() {
+	t.mu.Lock()
+	t.stopped = true
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	t.mu.Unlock()
+	<-t.done
+}
+
+// waitForPort polls until a TCP address accepts connections
+func waitForPort(ctx context.Context, address string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not reachable after %v: %v", address, timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// checkAPIServer verifies the API server answers through the configured path
+func checkAPIServer(ctx context.Context, client kubernetes.Interface) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiHealthTimeout)
+	defer cancel()
+
+	body, err := client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", &Error{Kind: KindClusterUnreachable, Err: fmt.Errorf("API server did not respond within %v", apiHealthTimeout)}
+		}
+		return "", err
+	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("unexpected version response: %v", err)
+	}
+	return info.GitVersion, nil
+}
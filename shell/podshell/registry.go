@@ -0,0 +1,155 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Action is an entry of the interactive menu. Built-in actions are registered
+// by NewAccessPods; other actions can be added with Register or
+// AccessPods.Commands.Register.
+type Action struct {
+	Name             string       // Unique identifier, e.g. "list-pods"
+	Description      string       // Text shown in the menu
+	Category         string       // Menu section the action is listed under; defaults to "Other"
+	Mutating         bool         // Changes cluster state; protected environments ask for confirmation
+	RefusesProtected bool         // Refuses protected environments by itself, so is not confirmed there
+	Permissions      []Permission // RBAC permissions needed in the namespace; missing ones disable the action
+	Handler          func(ctx context.Context, s *Session) error
+
+	run runAction // Non-interactive form for --run; built-in actions only
+}
+
+// Session is the connected environment as seen by an action handler.
+// The handler's ctx is cancelled when the user presses Ctrl+C.
+type Session struct {
+	Env       string
+	Namespace string
+	Protected bool
+	Timeout   time.Duration // Limit for a single backend operation; zero means none
+
+	Client  kubernetes.Interface    // Kubernetes API client for the environment
+	Metrics metricsclient.Interface // metrics.k8s.io client
+
+	config ClusterConfig
+}
+
+// Kubectl runs a non-interactive kubectl command against the environment,
+// bounded by the session timeout. With out nil the output is returned;
+// otherwise it is streamed to out.
+func (s *Session) Kubectl(ctx context.Context, out io.Writer, args ...string) ([]byte, error) {
+	config := s.config
+	config.timeout = s.Timeout
+	return runKubectl(ctx, config, out, args...)
+}
+
+// SelectPod lets the user pick a pod of the namespace
+func (s *Session) SelectPod(ctx context.Context) (*corev1.Pod, error) {
+	return selectPodObject(ctx, s.Client, s.Namespace)
+}
+
+// Registry holds menu actions in registration order
+type Registry struct {
+	mu      sync.RWMutex
+	actions []Action
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds an action. Names must be unique and a handler is required.
+func (r *Registry) Register(action Action) error {
+	if action.Name == "" {
+		return fmt.Errorf("action name is required")
+	}
+	if action.Handler == nil {
+		return fmt.Errorf("action %s has no handler", action.Name)
+	}
+	if action.Description == "" {
+		action.Description = action.Name
+	}
+	if action.Category == "" {
+		action.Category = "Other"
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.actions {
+		if existing.Name == action.Name {
+			return fmt.Errorf("action %s is already registered", action.Name)
+		}
+	}
+	r.actions = append(r.actions, action)
+	return nil
+}
+
+// Actions returns the registered actions in registration order
+func (r *Registry) Actions() []Action {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Action(nil), r.actions...)
+}
+
+// Lookup returns the action with the given name
+func (r *Registry) Lookup(name string) (Action, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, action := range r.actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// menu returns the actions grouped by category, categories in order of first registration
+func (r *Registry) menu() []Action {
+	actions := r.Actions()
+	var categories []string
+	byCategory := map[string][]Action{}
+	for _, action := range actions {
+		if _, ok := byCategory[action.Category]; !ok {
+			categories = append(categories, action.Category)
+		}
+		byCategory[action.Category] = append(byCategory[action.Category], action)
+	}
+
+	menu := make([]Action, 0, len(actions))
+	for _, category := range categories {
+		menu = append(menu, byCategory[category]...)
+	}
+	return menu
+}
+
+// defaultRegistry holds actions registered with Register
+var defaultRegistry = NewRegistry()
+
+// builtinActions are the actions registered by NewAccessPods, by name
+var builtinActions = func() map[string]Action {
+	a := &AccessPods{Commands: NewRegistry()}
+	a.registerCommands()
+	actions := map[string]Action{}
+	for _, action := range a.Commands.Actions() {
+		actions[action.Name] = action
+	}
+	return actions
+}()
+
+// Register adds an action to every AccessPods created afterwards, typically
+// from an init function of a package linked into the binary. Built-in
+// actions are listed first; their names cannot be reused.
+func Register(action Action) error {
+	if _, ok := builtinActions[action.Name]; ok {
+		return fmt.Errorf("action %s is a built-in action", action.Name)
+	}
+	return defaultRegistry.Register(action)
+}
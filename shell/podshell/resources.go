@@ -0,0 +1,359 @@
+package podshell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// resourcePatch is the strategic merge patch body used to update container resources
+type resourcePatch struct {
+	Spec resourcePatchSpec `json:"spec"`
+}
+
+type resourcePatchSpec struct {
+	Containers []resourcePatchContainer `json:"containers"`
+}
+
+type resourcePatchContainer struct {
+	Name      string                    `json:"name"`
+	Resources resourcePatchRequirements `json:"resources"`
+}
+
+// resourcePatchRequirements mirrors ResourceRequirements but allows null values,
+// which a strategic merge patch uses to remove an entry
+type resourcePatchRequirements struct {
+	Requests map[corev1.ResourceName]*resource.Quantity `json:"requests,omitempty"`
+	Limits   map[corev1.ResourceName]*resource.Quantity `json:"limits,omitempty"`
+}
+
+// resourceChange describes an update to one container's resources.
+// Requests and Limits are set; RemoveLimits lists limits to delete.
+type resourceChange struct {
+	Requests     corev1.ResourceList
+	Limits       corev1.ResourceList
+	RemoveLimits []corev1.ResourceName
+}
+
+// adjustPodResource prompts for a new request and limit of a single resource for a
+// container of a selected pod, validates them and shows the resulting QoS class
+// before applying the change.
+func (a *AccessPods) adjustPodResource(ctx context.Context, namespace string, name corev1.ResourceName, example string) error {
+	pod, err := selectPodObject(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+	container, err := selectContainer(pod)
+	if err != nil {
+		return err
+	}
+	current := containerResources(pod, container)
+
+	// Show current resource values
+	fmt.Printf("\nCurrent %s request/limit: %s\n", name, formatResourcePair(current, name))
+
+	// Prompt for the new request and limit independently
+	change := resourceChange{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	request, _, err := promptQuantity(fmt.Sprintf("Enter new %s request (%s, empty to keep): ", name, example), name, false)
+	if err != nil {
+		return err
+	}
+	if request != nil {
+		change.Requests[name] = *request
+	}
+	limit, remove, err := promptQuantity(fmt.Sprintf("Enter new %s limit (%s, empty to keep, '-' to remove): ", name, example), name, true)
+	if err != nil {
+		return err
+	}
+	switch {
+	case remove:
+		change.RemoveLimits = append(change.RemoveLimits, name)
+	case limit != nil:
+		change.Limits[name] = *limit
+	}
+	if request == nil && limit == nil && !remove {
+		fmt.Println("No changes entered")
+		return nil
+	}
+
+	return a.applyValidatedResources(ctx, pod, container, change)
+}
+
+// promptQuantity reads a quantity from the user. Empty input returns nil; when
+// allowRemove is set, '-' requests removal of the value.
+func promptQuantity(prompt string, name corev1.ResourceName, allowRemove bool) (*resource.Quantity, bool, error) {
+	fmt.Printf("\n%s", prompt)
+	var input string
+	fmt.Scanln(&input)
+	input = strings.TrimSpace(input)
+	switch {
+	case input == "":
+		return nil, false, nil
+	case input == "-" && allowRemove:
+		return nil, true, nil
+	}
+	quantity, err := parseResourceQuantity(name, input)
+	if err != nil {
+		return nil, false, err
+	}
+	return &quantity, false, nil
+}
+
+// applyValidatedResources merges a resource change with the container's current
+// resources, validates the result against the namespace policies, shows the
+// resulting QoS class and patches the pod after confirmation.
+func (a *AccessPods) applyValidatedResources(ctx context.Context, pod *corev1.Pod, container string, change resourceChange) error {
+	current := containerResources(pod, container)
+	desired := mergeResources(current, change)
+	if err := validateResources(ctx, a.client, pod.Namespace, current, desired); err != nil {
+		return err
+	}
+
+	// QoS class is derived from all containers, so evaluate the pod with the change applied
+	updated := pod.DeepCopy()
+	for i := range updated.Spec.Containers {
+		if updated.Spec.Containers[i].Name == container {
+			updated.Spec.Containers[i].Resources = desired
+		}
+	}
+	oldQOS, newQOS := podQOSClass(pod), podQOSClass(updated)
+	fmt.Printf("\nResulting %s resources: ", container)
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+		fmt.Printf("%s=%s ", name, formatResourcePair(desired, name))
+	}
+	fmt.Printf("\nQoS class: %s -> %s\n", oldQOS, newQOS)
+	if oldQOS != newQOS {
+		fmt.Printf("%sWarning: the QoS class of a running pod cannot change; update the owning workload instead%s\n", colorYellow, colorReset)
+	}
+	if !a.getUserConfirmation("Apply changes? (y/n): ") {
+		return fmt.Errorf("operation cancelled by user")
+	}
+
+	return applyContainerResources(ctx, a.config, pod.Name, pod.Namespace, container, change)
+}
+
+// containerResources returns the resources of a named container in the pod
+func containerResources(pod *corev1.Pod, container string) corev1.ResourceRequirements {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return c.Resources
+		}
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// parseResourceQuantity parses user input as a Kubernetes quantity, rejecting
+// malformed, zero and negative values.
+func parseResourceQuantity(name corev1.ResourceName, input string) (resource.Quantity, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return resource.Quantity{}, invalidInput("no %s value entered", name)
+	}
+	quantity, err := resource.ParseQuantity(input)
+	if err != nil {
+		return resource.Quantity{}, invalidInput("invalid %s value %q: %v", name, input, err)
+	}
+	if quantity.Sign() <= 0 {
+		return resource.Quantity{}, invalidInput("%s value must be greater than zero", name)
+	}
+	return quantity, nil
+}
+
+// mergeResources overlays the values in change on top of current
+func mergeResources(current corev1.ResourceRequirements, change resourceChange) corev1.ResourceRequirements {
+	merged := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	for k, v := range current.Requests {
+		merged.Requests[k] = v
+	}
+	for k, v := range current.Limits {
+		merged.Limits[k] = v
+	}
+	for k, v := range change.Requests {
+		merged.Requests[k] = v
+	}
+	for k, v := range change.Limits {
+		merged.Limits[k] = v
+	}
+	for _, k := range change.RemoveLimits {
+		delete(merged.Limits, k)
+	}
+	return merged
+}
+
+// podQOSClass computes the QoS class of a pod from its containers' CPU and memory
+// resources, following the rules used by the kubelet.
+func podQOSClass(pod *corev1.Pod) corev1.PodQOSClass {
+	qosResources := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+	hasAny := false
+	guaranteed := true
+	for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		for _, name := range qosResources {
+			request, hasRequest := c.Resources.Requests[name]
+			limit, hasLimit := c.Resources.Limits[name]
+			if hasRequest || hasLimit {
+				hasAny = true
+			}
+			// Requests default to limits when only the limit is set
+			if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case !hasAny:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
+
+// validateResources checks that requests do not exceed limits and that the desired
+// resources satisfy the namespace LimitRanges and fit the remaining ResourceQuota.
+// All problems are reported together. Users not allowed to list LimitRanges or
+// ResourceQuotas are warned and the API server has the final say.
+func validateResources(ctx context.Context, client kubernetes.Interface, namespace string, current, desired corev1.ResourceRequirements) error {
+	var problems []string
+
+	for name, request := range desired.Requests {
+		if limit, ok := desired.Limits[name]; ok && request.Cmp(limit) > 0 {
+			problems = append(problems, fmt.Sprintf("%s request %s exceeds limit %s", name, request.String(), limit.String()))
+		}
+	}
+
+	limitRanges, err := client.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		fmt.Printf("%sWarning: not allowed to list LimitRanges; skipping the LimitRange check%s\n", colorYellow, colorReset)
+	case err != nil:
+		return fmt.Errorf("failed to read LimitRanges: %w", err)
+	default:
+		for _, lr := range limitRanges.Items {
+			problems = append(problems, checkLimitRange(lr, desired)...)
+		}
+	}
+
+	quotas, err := client.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		fmt.Printf("%sWarning: not allowed to list ResourceQuotas; skipping the quota check%s\n", colorYellow, colorReset)
+	case err != nil:
+		return fmt.Errorf("failed to read ResourceQuotas: %w", err)
+	default:
+		for _, quota := range quotas.Items {
+			problems = append(problems, checkQuota(quota, current, desired)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return invalidInput("invalid resources:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// checkLimitRange validates container resources against the Container items of a LimitRange
+func checkLimitRange(lr corev1.LimitRange, desired corev1.ResourceRequirements) []string {
+	var problems []string
+	for _, item := range lr.Spec.Limits {
+		if item.Type != corev1.LimitTypeContainer {
+			continue
+		}
+		for name, min := range item.Min {
+			if q, ok := desired.Requests[name]; ok && q.Cmp(min) < 0 {
+				problems = append(problems, fmt.Sprintf("%s request %s is below LimitRange %s minimum %s", name, q.String(), lr.Name, min.String()))
+			}
+		}
+		for name, max := range item.Max {
+			if q, ok := desired.Limits[name]; ok && q.Cmp(max) > 0 {
+				problems = append(problems, fmt.Sprintf("%s limit %s exceeds LimitRange %s maximum %s", name, q.String(), lr.Name, max.String()))
+			}
+		}
+		for name, ratio := range item.MaxLimitRequestRatio {
+			request, hasRequest := desired.Requests[name]
+			limit, hasLimit := desired.Limits[name]
+			if !hasRequest || !hasLimit || request.IsZero() {
+				continue
+			}
+			if float64(limit.MilliValue())/float64(request.MilliValue()) > ratio.AsApproximateFloat64() {
+				problems = append(problems, fmt.Sprintf("%s limit/request ratio exceeds LimitRange %s maximum %s", name, lr.Name, ratio.String()))
+			}
+		}
+	}
+	return problems
+}
+
+// checkQuota verifies that the increase from current to desired fits in the remaining quota
+func checkQuota(quota corev1.ResourceQuota, current, desired corev1.ResourceRequirements) []string {
+	var problems []string
+	check := func(quotaName corev1.ResourceName, name corev1.ResourceName, cur, want corev1.ResourceList) {
+		hard, ok := quota.Status.Hard[quotaName]
+		if !ok {
+			return
+		}
+		newValue, ok := want[name]
+		if !ok {
+			return
+		}
+		delta := newValue.DeepCopy()
+		if old, ok := cur[name]; ok {
+			delta.Sub(old)
+		}
+		if delta.Sign() <= 0 {
+			return
+		}
+		used := quota.Status.Used[quotaName]
+		total := used.DeepCopy()
+		total.Add(delta)
+		if total.Cmp(hard) > 0 {
+			problems = append(problems, fmt.Sprintf("ResourceQuota %s: %s would reach %s (hard limit %s)", quota.Name, quotaName, total.String(), hard.String()))
+		}
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+		check(name, name, current.Requests, desired.Requests)
+		check(corev1.ResourceName("requests."+string(name)), name, current.Requests, desired.Requests)
+		check(corev1.ResourceName("limits."+string(name)), name, current.Limits, desired.Limits)
+	}
+	return problems
+}
+
+// applyContainerResources patches the requests and limits of one container in a pod.
+// Resources not mentioned in the change are left unchanged.
+func applyContainerResources(ctx context.Context, config ClusterConfig, pod, namespace, container string, change resourceChange) error {
+	requirements := resourcePatchRequirements{
+		Requests: map[corev1.ResourceName]*resource.Quantity{},
+		Limits:   map[corev1.ResourceName]*resource.Quantity{},
+	}
+	for k, v := range change.Requests {
+		requirements.Requests[k] = &v
+	}
+	for k, v := range change.Limits {
+		requirements.Limits[k] = &v
+	}
+	for _, k := range change.RemoveLimits {
+		requirements.Limits[k] = nil
+	}
+
+	patch := resourcePatch{Spec: resourcePatchSpec{Containers: []resourcePatchContainer{{
+		Name:      container,
+		Resources: requirements,
+	}}}}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to build resource patch: %v", err)
+	}
+
+	_, err = runKubectl(ctx, config, os.Stdout, "patch", "pod", pod, "-n", namespace, "--type=strategic", "-p", string(body))
+	return err
+}
@@ -0,0 +1,207 @@
+package podshell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// Retry defaults and backoff limits
+const (
+	defaultRetries = 3
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// retryPolicy retries transient failures of idempotent operations with
+// exponential backoff and jitter
+type retryPolicy struct {
+	env     string
+	retries int  // Retries after the first attempt; zero disables retrying
+	verbose bool // Report each retry on stderr
+}
+
+// retryPolicy returns the environment's policy; environments without a
+// retries setting that were not loaded through configurations do not retry
+func (c ClusterConfig) retryPolicy() retryPolicy {
+	p := retryPolicy{env: c.env, verbose: c.verbose}
+	if c.retries != nil {
+		p.retries = *c.retries
+	}
+	return p
+}
+
+// backoff returns the delay before the given retry (starting at 0): it doubles
+// each time up to retryMaxDelay, with the lower half randomized so that
+// clients failing together do not retry together
+func backoff(retry int) time.Duration {
+	d := retryMaxDelay
+	if retry < 16 && retryBaseDelay<<retry < retryMaxDelay {
+		d = retryBaseDelay << retry
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// do runs fn until it succeeds, fails with a permanent error, runs out of
+// retries or ctx ends. The last error is returned.
+func (p retryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	for retry := 0; ; retry++ {
+		err := fn()
+		if err == nil || retry >= p.retries || ctx.Err() != nil || !isTransient(err) {
+			return err
+		}
+		delay := backoff(retry)
+		p.report(op, retry+1, delay, err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// report prints a retry in verbose mode
+func (p retryPolicy) report(op string, retry int, delay time.Duration, cause string) {
+	if !p.verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s%s: %s failed (%s), retry %d/%d in %v%s\n",
+		colorGrey, p.env, op, cause, retry, p.retries, delay.Round(time.Millisecond), colorReset)
+}
+
+// droppedConnectionMessages are fragments of transport errors for connections
+// that broke off and usually succeed when repeated
+var droppedConnectionMessages = []string{
+	"connection reset by peer",
+	"TLS handshake timeout",
+	"unexpected EOF",
+	"http2: server sent GOAWAY",
+}
+
+// transientMessages are fragments of gcloud, kubectl and client-go messages
+// for other failures that usually succeed when repeated
+var transientMessages = []string{
+	"TooManyRequests",
+	"Too Many Requests",
+	"ServiceUnavailable",
+	"Service Unavailable",
+	"HTTPError 429",
+	"HTTPError 503",
+	"RESOURCE_EXHAUSTED",
+	"rateLimitExceeded",
+	// gcloud token refresh races when several commands refresh at once
+	"There was a problem refreshing your current auth tokens",
+}
+
+// isTransient reports whether a failure is worth retrying: throttling,
+// unavailable servers, dropped connections and token refresh races. Errors
+// already classified by podshell, such as timeouts, are permanent.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return false
+	}
+	if apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) {
+		return true
+	}
+	return containsAny(err.Error(), transientMessages) || containsAny(err.Error(), droppedConnectionMessages)
+}
+
+// containsAny reports whether msg contains one of the fragments
+func containsAny(msg string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRetry retries the client's GET requests on transient failures.
+// client-go only retries responses carrying a Retry-After header.
+func applyRetry(restConfig *rest.Config, config ClusterConfig) {
+	policy := config.retryPolicy()
+	if policy.retries == 0 {
+		return
+	}
+	restConfig.Wrap(func(next http.RoundTripper) http.RoundTripper {
+		return &retryTransport{next: next, policy: policy}
+	})
+}
+
+// retryTransport retries GET requests that fail with a transient status or
+// transport error. Other methods are not idempotent and pass through.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy retryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for retry := 0; ; retry++ {
+		resp, err := t.next.RoundTrip(req)
+		cause := retryCause(resp, err)
+		if cause == "" || retry >= t.policy.retries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := backoff(retry)
+		if resp != nil {
+			if after := retryAfter(resp); after > delay {
+				delay = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.policy.report("GET "+req.URL.Path, retry+1, delay, cause)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryCause describes a transient response or dropped connection, or returns
+// an empty string when the result should be returned as is. Other transport
+// errors, such as refused connections or certificate and proxy failures, do
+// not go away by retrying.
+func retryCause(resp *http.Response, err error) string {
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || containsAny(err.Error(), droppedConnectionMessages) {
+			return err.Error()
+		}
+		return ""
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status
+	}
+	return ""
+}
+
+// retryAfter returns the delay requested by a Retry-After header in seconds,
+// capped at retryMaxDelay
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, retryMaxDelay)
+}
@@ -0,0 +1,224 @@
+package podshell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// flaky returns a fn for retryPolicy.do that fails with errs in turn and
+// then succeeds, counting the attempts
+func flaky(attempts *int, errs ...error) func() error {
+	return func() error {
+		*attempts++
+		if *attempts <= len(errs) {
+			return errs[*attempts-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	throttled := apierrors.NewTooManyRequests("slow down", 0)
+	unavailable := apierrors.NewServiceUnavailable("try later")
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "api", errors.New("denied"))
+
+	tests := []struct {
+		name     string
+		retries  int
+		errs     []error
+		attempts int
+		wantErr  error
+	}{
+		{"succeeds after transient failures", 3, []error{throttled, unavailable}, 3, nil},
+		{"stops on a permanent error", 3, []error{forbidden, throttled}, 1, forbidden},
+		{"stops when out of retries", 1, []error{unavailable, unavailable, unavailable}, 2, unavailable},
+		{"disabled", 0, []error{throttled}, 1, throttled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retryPolicy{retries: tt.retries}.do(context.Background(), "test", flaky(&attempts, tt.errs...))
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if err != tt.wantErr {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("stops when ctx is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := retryPolicy{retries: 3}.do(ctx, "test", func() error {
+			attempts++
+			cancel()
+			return unavailable
+		})
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+		if err != unavailable {
+			t.Errorf("err = %v, want %v", err, unavailable)
+		}
+	})
+
+	t.Run("stops waiting when ctx is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		attempts := 0
+		start := time.Now()
+		retryPolicy{retries: 3}.do(ctx, "test", flaky(&attempts, unavailable, unavailable))
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+		if elapsed := time.Since(start); elapsed >= retryBaseDelay/2 {
+			t.Errorf("returned after %v, want before the first backoff ends", elapsed)
+		}
+	})
+}
+
+// flakyServer answers with statuses in turn and then 200, counting the requests
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if int(n) <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransport(t *testing.T) {
+	client := func(next http.RoundTripper) *http.Client {
+		return &http.Client{Transport: &retryTransport{next: next, policy: retryPolicy{retries: 3}}}
+	}
+
+	t.Run("retries GET until it succeeds", func(t *testing.T) {
+		server, requests := flakyServer(t, nil, http.StatusServiceUnavailable)
+		resp, err := client(http.DefaultTransport).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d, want 200", resp.StatusCode)
+		}
+		if *requests != 2 {
+			t.Errorf("requests = %d, want 2", *requests)
+		}
+	})
+
+	t.Run("never retries POST", func(t *testing.T) {
+		server, requests := flakyServer(t, nil, http.StatusServiceUnavailable)
+		resp, err := client(http.DefaultTransport).Post(server.URL, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", resp.StatusCode)
+		}
+		if *requests != 1 {
+			t.Errorf("requests = %d, want 1", *requests)
+		}
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		header := http.Header{"Retry-After": {"2"}}
+		server, requests := flakyServer(t, header, http.StatusTooManyRequests)
+		start := time.Now()
+		resp, err := client(http.DefaultTransport).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if elapsed := time.Since(start); elapsed < 2*time.Second {
+			t.Errorf("retried after %v, want at least 2s", elapsed)
+		}
+		if *requests != 2 {
+			t.Errorf("requests = %d, want 2", *requests)
+		}
+	})
+
+	t.Run("retries dropped connections only", func(t *testing.T) {
+		tests := []struct {
+			err      error
+			attempts int
+		}{
+			{io.ErrUnexpectedEOF, 2},
+			{errors.New("read tcp: connection reset by peer"), 2},
+			{errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), 1},
+			{errors.New("tls: failed to verify certificate: x509: certificate signed by unknown authority"), 1},
+			{errors.New("proxyconnect tcp: Proxy Authentication Required"), 1},
+		}
+		for _, tt := range tests {
+			attempts := 0
+			next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, tt.err
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			})
+			req, _ := http.NewRequest(http.MethodGet, "https://cluster.example/version", nil)
+			if resp, err := (&retryTransport{next: next, policy: retryPolicy{retries: 3}}).RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+			if attempts != tt.attempts {
+				t.Errorf("%v: attempts = %d, want %d", tt.err, attempts, tt.attempts)
+			}
+		}
+	})
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{apierrors.NewTooManyRequests("slow down", 1), true},
+		{apierrors.NewServiceUnavailable("try later"), true},
+		{errors.New("ERROR: (gcloud.container.clusters.get-credentials) HTTPError 503: unavailable"), true},
+		{errors.New("read tcp 10.0.0.2:51234->10.0.0.1:443: read: connection reset by peer"), true},
+		{errors.New("http2: server sent GOAWAY and closed the connection"), true},
+		{errors.New("There was a problem refreshing your current auth tokens"), true},
+		{apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "api", errors.New("denied")), false},
+		{apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "api"), false},
+		{errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+		{&Error{Kind: KindTimeout, Err: errors.New("timed out after 1m0s")}, false},
+		{&Error{Kind: KindClusterUnreachable, Err: errors.New("connection reset by peer")}, false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Default sampling settings for right-sizing; metrics-server refreshes roughly every 15s
+const (
+	defaultSampleWindow   = 5 * time.Minute
+	defaultSampleInterval = 15 * time.Second
+)
+
+// Headroom applied on top of observed usage when proposing new values
+const (
+	cpuLimitHeadroom      = 1.5
+	memoryRequestHeadroom = 1.1
+	memoryLimitHeadroom   = 1.25
+)
+
+// Lower bounds so proposals never drop below a workable size
+const (
+	minCPUMilli    = 10
+	minMemoryBytes = 32 * 1024 * 1024
+)
+
+// usageSamples holds the CPU (millicores) and memory (bytes) samples of one container
+type usageSamples struct {
+	CPU    []int64
+	Memory []int64
+}
+
+// usageStats summarizes a series of samples
+type usageStats struct {
+	P50, P90, P95, P99, Max int64
+}
+
+// sizingProposal is the recommended resources for one container
+type sizingProposal struct {
+	Container string
+	Samples   int
+	CPU       usageStats
+	Memory    usageStats
+	Current   corev1.ResourceRequirements
+	Proposed  corev1.ResourceRequirements
+}
+
+// rightSizePod samples the usage of a selected pod over a configurable window,
+// proposes new requests/limits per container and optionally applies them.
+func (a *AccessPods) rightSizePod(ctx context.Context, namespace string) error {
+	pod, err := selectPodObject(ctx, a.client, namespace)
+	if err != nil {
+		return err
+	}
+
+	window, err := promptDuration("Sampling window", defaultSampleWindow)
+	if err != nil {
+		return err
+	}
+	interval, err := promptDuration("Sample interval", defaultSampleInterval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 || interval > window {
+		return invalidInput("sample interval must be between 0 and the sampling window")
+	}
+
+	samples, err := sampleUsage(ctx, a.metrics, namespace, pod.Name, window, interval)
+	if err != nil {
+		return err
+	}
+
+	proposals := proposeResources(pod, samples)
+	printProposals(proposals)
+
+	// Offer to apply each proposal through the resource adjustment flow
+	for _, p := range proposals {
+		if p.Samples == 0 {
+			continue
+		}
+		if !a.getUserConfirmation(fmt.Sprintf("Apply proposal to container %s? (y/n): ", p.Container)) {
+			continue
+		}
+		change := resourceChange{Requests: p.Proposed.Requests, Limits: p.Proposed.Limits}
+		if err := a.applyValidatedResources(ctx, pod, p.Container, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promptDuration asks for a duration such as 30s or 10m, falling back to a default
+func promptDuration(label string, def time.Duration) (time.Duration, error) {
+	fmt.Printf("\n%s (default %s): ", label, def)
+	var input string
+	fmt.Scanln(&input)
+	if input == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(input)
+	if err != nil {
+		return 0, invalidInput("invalid duration %q: %v", input, err)
+	}
+	return d, nil
+}
+
+// sampleUsage polls the metrics API for a pod every interval until the window
+// has elapsed and returns the samples per container. Failed polls are skipped.
+func sampleUsage(ctx context.Context, metrics metricsclient.Interface, namespace, pod string, window, interval time.Duration) (map[string]*usageSamples, error) {
+	samples := make(map[string]*usageSamples)
+	deadline := time.Now().Add(window)
+	total := int(window / interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 1; ; i++ {
+		pm, err := metrics.MetricsV1beta1().PodMetricses(namespace).Get(ctx, pod, metav1.GetOptions{})
+		if err != nil {
+			fmt.Printf("%sSample %d/%d failed: %v%s\n", colorRed, i, total, err, colorReset)
+		} else {
+			for _, c := range pm.Containers {
+				s, ok := samples[c.Name]
+				if !ok {
+					s = &usageSamples{}
+					samples[c.Name] = s
+				}
+				s.CPU = append(s.CPU, c.Usage.Cpu().MilliValue())
+				s.Memory = append(s.Memory, c.Usage.Memory().Value())
+			}
+			fmt.Printf("\rCollected sample %d/%d", i, total)
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	fmt.Println()
+
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no usage samples collected for pod %s", pod)
+	}
+	return samples, nil
+}
+
+// computeStats returns percentiles of the samples using the nearest-rank method
+func computeStats(values []int64) usageStats {
+	if len(values) == 0 {
+		return usageStats{}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return usageStats{
+		P50: percentile(sorted, 50),
+		P90: percentile(sorted, 90),
+		P95: percentile(sorted, 95),
+		P99: percentile(sorted, 99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// proposeResources derives new requests/limits for each container of the pod:
+// CPU request at p90 with limit at p99 plus headroom, memory request at p95 plus
+// headroom and memory limit at the observed peak plus headroom.
+func proposeResources(pod *corev1.Pod, samples map[string]*usageSamples) []sizingProposal {
+	var proposals []sizingProposal
+	for _, c := range pod.Spec.Containers {
+		p := sizingProposal{Container: c.Name, Current: c.Resources}
+		s, ok := samples[c.Name]
+		if !ok {
+			proposals = append(proposals, p)
+			continue
+		}
+		p.Samples = len(s.CPU)
+		p.CPU = computeStats(s.CPU)
+		p.Memory = computeStats(s.Memory)
+
+		cpuRequest := max(p.CPU.P90, minCPUMilli)
+		cpuLimit := max(int64(float64(p.CPU.P99)*cpuLimitHeadroom), cpuRequest)
+		memRequest := max(roundUpMi(int64(float64(p.Memory.P95)*memoryRequestHeadroom)), minMemoryBytes)
+		memLimit := max(roundUpMi(int64(float64(p.Memory.Max)*memoryLimitHeadroom)), memRequest)
+
+		p.Proposed = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewMilliQuantity(cpuRequest, resource.DecimalSI),
+				corev1.ResourceMemory: *resource.NewQuantity(memRequest, resource.BinarySI),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewMilliQuantity(cpuLimit, resource.DecimalSI),
+				corev1.ResourceMemory: *resource.NewQuantity(memLimit, resource.BinarySI),
+			},
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals
+}
+
+// roundUpMi rounds bytes up to a whole mebibyte
+func roundUpMi(bytes int64) int64 {
+	const mi = 1024 * 1024
+	return (bytes + mi - 1) / mi * mi
+}
+
+// printProposals prints observed percentiles alongside current and proposed values
+func printProposals(proposals []sizingProposal) {
+	fmt.Printf("\n%sObserved usage:%s\n", colorYellow, colorReset)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tSAMPLES\tCPU P50/P90/P99/MAX\tMEMORY P50/P95/MAX")
+	for _, p := range proposals {
+		fmt.Fprintf(w, "%s\t%d\t%s/%s/%s/%s\t%s/%s/%s\n", p.Container, p.Samples,
+			formatMilliCPU(p.CPU.P50), formatMilliCPU(p.CPU.P90), formatMilliCPU(p.CPU.P99), formatMilliCPU(p.CPU.Max),
+			formatBytes(p.Memory.P50), formatBytes(p.Memory.P95), formatBytes(p.Memory.Max))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%sProposed resources:%s\n", colorYellow, colorReset)
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tCPU REQ/LIM (current)\tCPU REQ/LIM (proposed)\tMEM REQ/LIM (current)\tMEM REQ/LIM (proposed)")
+	for _, p := range proposals {
+		if p.Samples == 0 {
+			fmt.Fprintf(w, "%s\tno samples\t\t\t\n", p.Container)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Container,
+			formatResourcePair(p.Current, corev1.ResourceCPU), formatResourcePair(p.Proposed, corev1.ResourceCPU),
+			formatResourcePair(p.Current, corev1.ResourceMemory), formatResourcePair(p.Proposed, corev1.ResourceMemory))
+	}
+	w.Flush()
+}
+
+// formatResourcePair renders request/limit for one resource, using '-' for unset values
+func formatResourcePair(res corev1.ResourceRequirements, name corev1.ResourceName) string {
+	value := func(list corev1.ResourceList) string {
+		if q, ok := list[name]; ok {
+			return q.String()
+		}
+		return "-"
+	}
+	return value(res.Requests) + "/" + value(res.Limits)
+}
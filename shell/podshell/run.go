@@ -0,0 +1,477 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// RunOptions configures a single non-interactive action
+type RunOptions struct {
+	Env       string // Environment name from the configuration file
+	Action    string // Name of a menu action, one of RunActions
+	Pod       string // Target pod for pod-scoped actions
+	Container string // Target container; defaults to the first container
+	Filter    string // Substring filter for the env action
+	Reveal    bool   // Show secret-derived env values
+	Output    string // Output format: table, wide, json or yaml
+}
+
+// runAction produces the result of a non-interactive action
+type runAction func(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error)
+
+// RunActions returns the names of the menu actions available in non-interactive mode
+func RunActions() []string {
+	var names []string
+	for name, action := range builtinActions {
+		if action.run != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// showResult adapts a non-interactive action to the menu, printing its wide table
+func (a *AccessPods) showResult(run runAction) func(ctx context.Context, namespace string) error {
+	return func(ctx context.Context, namespace string) error {
+		result, err := run(ctx, a, namespace, RunOptions{})
+		if err != nil {
+			return err
+		}
+		return writeOutput(os.Stdout, outputWide, result)
+	}
+}
+
+// Run connects to the named environment without prompting, executes one action
+// and writes its result to stdout in the requested output format.
+func (a *AccessPods) Run(ctx context.Context, opts RunOptions) error {
+	format, err := parseOutputFormat(opts.Output)
+	if err != nil {
+		return err
+	}
+	action, ok := a.Commands.Lookup(opts.Action)
+	if !ok || action.run == nil {
+		return invalidInput("unknown action %q (expected one of %s)", opts.Action, strings.Join(RunActions(), ", "))
+	}
+
+	configs, err := a.configurations()
+	if err != nil {
+		return err
+	}
+	config, err := findConfiguration(configs, opts.Env)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+	if _, err := a.connect(ctx, config); err != nil {
+		return err
+	}
+
+	result, err := action.run(ctx, a, config.namespace, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, format, result)
+}
+
+// findConfiguration returns the configuration of the named environment
+func findConfiguration(configs []ClusterConfig, env string) (ClusterConfig, error) {
+	if env == "" {
+		return ClusterConfig{}, invalidInput("an environment name is required in non-interactive mode")
+	}
+	for _, config := range configs {
+		if config.env == env {
+			return config, nil
+		}
+	}
+	return ClusterConfig{}, &Error{
+		Kind: KindNotFound,
+		Err:  fmt.Errorf("environment %q not found in configuration", env),
+		Hint: "run config path to see which configuration files were loaded",
+	}
+}
+
+// getPodByName fetches the pod named in the options
+func (a *AccessPods) getPodByName(ctx context.Context, namespace string, opts RunOptions) (*corev1.Pod, error) {
+	if opts.Pod == "" {
+		return nil, invalidInput("action %s requires a pod name", opts.Action)
+	}
+	return a.client.CoreV1().Pods(namespace).Get(ctx, opts.Pod, metav1.GetOptions{})
+}
+
+// podSummary is the stable schema of a pod in the list-pods action
+type podSummary struct {
+	Name     string    `json:"name"`
+	Phase    string    `json:"phase"`
+	Ready    string    `json:"ready"`
+	Restarts int32     `json:"restarts"`
+	Created  time.Time `json:"created"`
+	Node     string    `json:"node"`
+	PodIP    string    `json:"podIP"`
+	Images   []string  `json:"images"`
+}
+
+// podListOutput is the result of the list-pods action
+type podListOutput struct {
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace"`
+	Items     []podSummary `json:"items"`
+}
+
+func runListPods(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error) {
+	pods, err := a.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	out := &podListOutput{Kind: "PodList", Namespace: namespace, Items: []podSummary{}}
+	for _, p := range pods.Items {
+		s := podSummary{
+			Name:    p.Name,
+			Phase:   string(p.Status.Phase),
+			Created: p.CreationTimestamp.UTC(),
+			Node:    p.Spec.NodeName,
+			PodIP:   p.Status.PodIP,
+		}
+		ready := 0
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			s.Restarts += cs.RestartCount
+		}
+		s.Ready = fmt.Sprintf("%d/%d", ready, len(p.Spec.Containers))
+		for _, c := range p.Spec.Containers {
+			s.Images = append(s.Images, c.Image)
+		}
+		out.Items = append(out.Items, s)
+	}
+	return out, nil
+}
+
+func (o *podListOutput) writeTable(out io.Writer, wide bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if wide {
+		fmt.Fprintln(w, "NAME\tREADY\tSTATUS\tRESTARTS\tAGE\tIP\tNODE\tIMAGES")
+	} else {
+		fmt.Fprintln(w, "NAME\tREADY\tSTATUS\tRESTARTS\tAGE")
+	}
+	for _, p := range o.Items {
+		age := duration.HumanDuration(time.Since(p.Created))
+		if wide {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", p.Name, p.Ready, p.Phase, p.Restarts, age, p.PodIP, p.Node, strings.Join(p.Images, ","))
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", p.Name, p.Ready, p.Phase, p.Restarts, age)
+		}
+	}
+	w.Flush()
+}
+
+// containerDescription is the stable schema of a container in the describe action
+type containerDescription struct {
+	Name         string                  `json:"name"`
+	Image        string                  `json:"image"`
+	Ready        bool                    `json:"ready"`
+	RestartCount int32                   `json:"restartCount"`
+	State        string                  `json:"state"`
+	LastState    string                  `json:"lastState,omitempty"`
+	Resources    resourceRequirementsOut `json:"resources"`
+}
+
+// resourceRequirementsOut renders requests and limits as plain strings
+type resourceRequirementsOut struct {
+	Requests map[string]string `json:"requests"`
+	Limits   map[string]string `json:"limits"`
+}
+
+// eventSummary is the stable schema of an event
+type eventSummary struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+}
+
+// podDescriptionOutput is the result of the describe action
+type podDescriptionOutput struct {
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace"`
+	Node       string                 `json:"node"`
+	Phase      string                 `json:"phase"`
+	QOSClass   string                 `json:"qosClass"`
+	Labels     map[string]string      `json:"labels"`
+	Containers []containerDescription `json:"containers"`
+	Events     []eventSummary         `json:"events"`
+	Findings   []finding              `json:"findings"`
+
+	diagnosis *podDiagnosis
+}
+
+func runDescribePod(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error) {
+	pod, err := a.getPodByName(ctx, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+	diag, err := gatherDiagnosis(ctx, a.client, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &podDescriptionOutput{
+		Kind:       "PodDescription",
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		Node:       pod.Spec.NodeName,
+		Phase:      string(pod.Status.Phase),
+		QOSClass:   string(podQOSClass(pod)),
+		Labels:     pod.Labels,
+		Containers: []containerDescription{},
+		Events:     []eventSummary{},
+		Findings:   diag.Findings,
+		diagnosis:  diag,
+	}
+	statuses := make(map[string]corev1.ContainerStatus, len(pod.Status.ContainerStatuses))
+	for _, cs := range pod.Status.ContainerStatuses {
+		statuses[cs.Name] = cs
+	}
+	for _, c := range pod.Spec.Containers {
+		cs := statuses[c.Name]
+		d := containerDescription{
+			Name:         c.Name,
+			Image:        c.Image,
+			Ready:        cs.Ready,
+			RestartCount: cs.RestartCount,
+			State:        containerState(cs.State),
+			Resources:    resourcesOut(c.Resources),
+		}
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			d.LastState = fmt.Sprintf("%s (exit %d)", t.Reason, t.ExitCode)
+		}
+		out.Containers = append(out.Containers, d)
+	}
+	for _, ev := range diag.Events {
+		out.Events = append(out.Events, eventSummary{Time: eventTime(ev).UTC(), Type: ev.Type, Reason: ev.Reason, Message: ev.Message})
+	}
+	if out.Findings == nil {
+		out.Findings = []finding{}
+	}
+	return out, nil
+}
+
+func (o *podDescriptionOutput) writeTable(out io.Writer, wide bool) {
+	printDiagnosis(out, o.diagnosis)
+}
+
+// resourcesOut converts resource requirements to the output schema
+func resourcesOut(res corev1.ResourceRequirements) resourceRequirementsOut {
+	out := resourceRequirementsOut{Requests: map[string]string{}, Limits: map[string]string{}}
+	for k, v := range res.Requests {
+		out.Requests[string(k)] = v.String()
+	}
+	for k, v := range res.Limits {
+		out.Limits[string(k)] = v.String()
+	}
+	return out
+}
+
+// envOutput is the result of the env action; secret values are masked unless revealed
+type envOutput struct {
+	Kind      string   `json:"kind"`
+	Pod       string   `json:"pod"`
+	Container string   `json:"container"`
+	Items     []envVar `json:"items"`
+}
+
+func runShowEnv(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error) {
+	pod, err := a.getPodByName(ctx, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+	container := opts.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	vars, err := resolvePodEnv(ctx, a.client, pod, container)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &envOutput{Kind: "EnvList", Pod: pod.Name, Container: container, Items: []envVar{}}
+	for _, v := range filterEnv(vars, opts.Filter, opts.Reveal) {
+		v.Value = v.displayValue(opts.Reveal)
+		out.Items = append(out.Items, v)
+	}
+	return out, nil
+}
+
+func (o *envOutput) writeTable(out io.Writer, wide bool) {
+	// Values are already masked when the output was built
+	printEnv(out, o.Items, true)
+}
+
+// containerResourceSummary is the stable schema of one container's resources
+type containerResourceSummary struct {
+	Pod       string                  `json:"pod"`
+	Container string                  `json:"container"`
+	QOSClass  string                  `json:"qosClass"`
+	Resources resourceRequirementsOut `json:"resources"`
+}
+
+// resourceListOutput is the result of the resources action
+type resourceListOutput struct {
+	Kind      string                     `json:"kind"`
+	Namespace string                     `json:"namespace"`
+	Items     []containerResourceSummary `json:"items"`
+}
+
+func runShowResources(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error) {
+	var pods []corev1.Pod
+	if opts.Pod != "" {
+		pod, err := a.getPodByName(ctx, namespace, opts)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, *pod)
+	} else {
+		list, err := a.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = list.Items
+	}
+
+	out := &resourceListOutput{Kind: "ContainerResourceList", Namespace: namespace, Items: []containerResourceSummary{}}
+	for i := range pods {
+		qos := string(podQOSClass(&pods[i]))
+		for _, c := range pods[i].Spec.Containers {
+			if opts.Container != "" && c.Name != opts.Container {
+				continue
+			}
+			out.Items = append(out.Items, containerResourceSummary{
+				Pod:       pods[i].Name,
+				Container: c.Name,
+				QOSClass:  qos,
+				Resources: resourcesOut(c.Resources),
+			})
+		}
+	}
+	return out, nil
+}
+
+func (o *resourceListOutput) writeTable(out io.Writer, wide bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "POD\tCONTAINER\tCPU REQ/LIM\tMEM REQ/LIM"
+	if wide {
+		header += "\tSTORAGE REQ/LIM\tQOS"
+	}
+	fmt.Fprintln(w, header)
+	pair := func(r resourceRequirementsOut, name corev1.ResourceName) string {
+		value := func(m map[string]string) string {
+			if v, ok := m[string(name)]; ok {
+				return v
+			}
+			return "-"
+		}
+		return value(r.Requests) + "/" + value(r.Limits)
+	}
+	for _, c := range o.Items {
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", c.Pod, c.Container, pair(c.Resources, corev1.ResourceCPU), pair(c.Resources, corev1.ResourceMemory))
+		if wide {
+			line += fmt.Sprintf("\t%s\t%s", pair(c.Resources, corev1.ResourceEphemeralStorage), c.QOSClass)
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
+
+// hpaSummary is the stable schema of an HPA attached to a workload
+type hpaSummary struct {
+	Name            string `json:"name"`
+	MinReplicas     int32  `json:"minReplicas"`
+	MaxReplicas     int32  `json:"maxReplicas"`
+	CurrentReplicas int32  `json:"currentReplicas"`
+	DesiredReplicas int32  `json:"desiredReplicas"`
+	Pinned          bool   `json:"pinned"`
+}
+
+// workloadReplicas is the stable schema of a workload in the replicas action
+type workloadReplicas struct {
+	Kind    string      `json:"kind"`
+	Name    string      `json:"name"`
+	Desired int32       `json:"desired"`
+	Ready   int32       `json:"ready"`
+	HPA     *hpaSummary `json:"hpa,omitempty"`
+}
+
+// replicaListOutput is the result of the replicas action
+type replicaListOutput struct {
+	Kind      string             `json:"kind"`
+	Namespace string             `json:"namespace"`
+	Items     []workloadReplicas `json:"items"`
+}
+
+func runShowReplicas(ctx context.Context, a *AccessPods, namespace string, opts RunOptions) (tableWriter, error) {
+	workloads, err := listScalableWorkloads(ctx, a.client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	hpas, err := a.client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &replicaListOutput{Kind: "WorkloadReplicaList", Namespace: namespace, Items: []workloadReplicas{}}
+	for _, wl := range workloads {
+		item := workloadReplicas{Kind: wl.Kind, Name: wl.Name, Desired: wl.Desired, Ready: wl.Ready}
+		for i := range hpas.Items {
+			hpa := &hpas.Items[i]
+			ref := hpa.Spec.ScaleTargetRef
+			if strings.EqualFold(ref.Kind, wl.Kind) && ref.Name == wl.Name {
+				_, pinned := hpa.Annotations[hpaPinAnnotation]
+				item.HPA = &hpaSummary{
+					Name:            hpa.Name,
+					MinReplicas:     hpaMinReplicas(hpa),
+					MaxReplicas:     hpa.Spec.MaxReplicas,
+					CurrentReplicas: hpa.Status.CurrentReplicas,
+					DesiredReplicas: hpa.Status.DesiredReplicas,
+					Pinned:          pinned,
+				}
+			}
+		}
+		out.Items = append(out.Items, item)
+	}
+	return out, nil
+}
+
+func (o *replicaListOutput) writeTable(out io.Writer, wide bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "KIND\tNAME\tREADY\tHPA"
+	if wide {
+		header += "\tMIN/MAX\tPINNED"
+	}
+	fmt.Fprintln(w, header)
+	for _, item := range o.Items {
+		hpa, minMax, pinned := "-", "-", "-"
+		if item.HPA != nil {
+			hpa = item.HPA.Name
+			minMax = fmt.Sprintf("%d/%d", item.HPA.MinReplicas, item.HPA.MaxReplicas)
+			pinned = fmt.Sprintf("%t", item.HPA.Pinned)
+		}
+		line := fmt.Sprintf("%s\t%s\t%d/%d\t%s", item.Kind, item.Name, item.Ready, item.Desired, hpa)
+		if wide {
+			line += fmt.Sprintf("\t%s\t%s", minMax, pinned)
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package podshell
+
+import "time"
+
+// stdinReadable reports stdin as readable without waiting: polling is not
+// available here, so the following read blocks until input arrives
+func stdinReadable(time.Duration) bool {
+	return true
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package podshell
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// stdinReadable waits up to timeout for input on stdin. Errors, such as a
+// closed stdin, report it readable so the following read returns them.
+func stdinReadable(timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(os.Stdin.Fd()), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err == unix.EINTR {
+		return false
+	}
+	return err != nil || n > 0
+}
@@ -0,0 +1,241 @@
+package podshell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Usage thresholds (fraction of the limit) above which containers are highlighted
+const (
+	cpuThrottleThreshold = 0.9
+	memoryOOMThreshold   = 0.9
+)
+
+// defaultTopInterval is the refresh interval used when the user enters nothing
+const defaultTopInterval = 5 * time.Second
+
+// inputPollInterval is how often a wait for stdin input checks for cancellation
+const inputPollInterval = 100 * time.Millisecond
+
+// containerUsage combines live usage from the metrics API with the container's requests and limits.
+// CPU values are in millicores and memory values in bytes; zero means unset.
+type containerUsage struct {
+	Pod        string
+	Container  string
+	CPUUsage   int64
+	CPURequest int64
+	CPULimit   int64
+	MemUsage   int64
+	MemRequest int64
+	MemLimit   int64
+}
+
+// cpuLimitRatio returns CPU usage as a fraction of the limit, or 0 when no limit is set
+func (u containerUsage) cpuLimitRatio() float64 {
+	if u.CPULimit == 0 {
+		return 0
+	}
+	return float64(u.CPUUsage) / float64(u.CPULimit)
+}
+
+// memLimitRatio returns memory usage as a fraction of the limit, or 0 when no limit is set
+func (u containerUsage) memLimitRatio() float64 {
+	if u.MemLimit == 0 {
+		return 0
+	}
+	return float64(u.MemUsage) / float64(u.MemLimit)
+}
+
+// status summarizes whether the container is close to being throttled or OOM killed
+func (u containerUsage) status() string {
+	switch {
+	case u.memLimitRatio() >= memoryOOMThreshold:
+		return "NEAR OOM"
+	case u.cpuLimitRatio() >= cpuThrottleThreshold:
+		return "THROTTLING"
+	}
+	return "OK"
+}
+
+// topPods shows a periodically refreshed, top-style view of container usage in the namespace.
+// The view refreshes until the user presses Enter or interrupts it.
+func (a *AccessPods) topPods(ctx context.Context, namespace string) error {
+	fmt.Printf("\nRefresh interval in seconds (default %d, 0 to show once): ", int(defaultTopInterval.Seconds()))
+	var input string
+	fmt.Scanln(&input)
+	interval := defaultTopInterval
+	if input != "" {
+		var seconds int
+		if _, err := fmt.Sscan(input, &seconds); err != nil || seconds < 0 {
+			return invalidInput("invalid refresh interval: %s", input)
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	render := func() error {
+		usage, err := collectUsage(ctx, a.client, a.metrics, namespace)
+		if err != nil {
+			return err
+		}
+		if interval > 0 {
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Printf("%sResource usage in %s at %s%s\n", colorYellow, namespace, time.Now().Format(time.TimeOnly), colorReset)
+		printUsage(usage)
+		return nil
+	}
+
+	// Fail fast when metrics are unavailable instead of entering the refresh loop
+	if err := render(); err != nil || interval == 0 {
+		return err
+	}
+
+	// Stop refreshing once the user presses Enter. The reader waits for input
+	// before reading so it can stop on cancellation without taking the next
+	// line meant for the menu, and it has exited by the time this returns.
+	readerCtx, stopReader := context.WithCancel(ctx)
+	entered := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		if waitForInput(readerCtx) {
+			var line string
+			fmt.Scanln(&line)
+			close(entered)
+		}
+	}()
+	defer func() {
+		stopReader()
+		<-exited
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fmt.Println("\nPress Enter to return to the menu")
+		select {
+		case <-entered:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		// Keep the loop alive on transient errors; the reader goroutine owns stdin until Enter
+		if err := render(); err != nil {
+			a.handleError("Refreshing usage", err)
+		}
+	}
+}
+
+// waitForInput waits until stdin has input and reports whether it does,
+// or returns false once ctx is done
+func waitForInput(ctx context.Context) bool {
+	for ctx.Err() == nil {
+		if stdinReadable(inputPollInterval) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectUsage joins pod metrics with pod specs and returns usage per container,
+// sorted by CPU usage (highest first).
+func collectUsage(ctx context.Context, client kubernetes.Interface, metrics metricsclient.Interface, namespace string) ([]containerUsage, error) {
+	podMetrics, err := metrics.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics (is metrics-server available?): %v", err)
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make(map[string]map[string]corev1.ResourceRequirements, len(pods.Items))
+	for _, pod := range pods.Items {
+		containers := make(map[string]corev1.ResourceRequirements, len(pod.Spec.Containers))
+		for _, c := range pod.Spec.Containers {
+			containers[c.Name] = c.Resources
+		}
+		specs[pod.Name] = containers
+	}
+
+	var usage []containerUsage
+	for _, pm := range podMetrics.Items {
+		for _, cm := range pm.Containers {
+			u := containerUsage{
+				Pod:       pm.Name,
+				Container: cm.Name,
+				CPUUsage:  cm.Usage.Cpu().MilliValue(),
+				MemUsage:  cm.Usage.Memory().Value(),
+			}
+			if res, ok := specs[pm.Name][cm.Name]; ok {
+				u.CPURequest = res.Requests.Cpu().MilliValue()
+				u.CPULimit = res.Limits.Cpu().MilliValue()
+				u.MemRequest = res.Requests.Memory().Value()
+				u.MemLimit = res.Limits.Memory().Value()
+			}
+			usage = append(usage, u)
+		}
+	}
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].CPUUsage > usage[j].CPUUsage })
+	return usage, nil
+}
+
+// printUsage prints container usage with requests/limits, highlighting containers at risk
+func printUsage(usage []containerUsage) {
+	if len(usage) == 0 {
+		fmt.Println("No metrics available")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POD\tCONTAINER\tCPU\tCPU REQ/LIM\tCPU%LIM\tMEMORY\tMEM REQ/LIM\tMEM%LIM\tSTATUS")
+	for _, u := range usage {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s/%s\t%s\t%s\t%s/%s\t%s\t%s\n",
+			u.Pod, u.Container,
+			formatMilliCPU(u.CPUUsage), formatMilliCPU(u.CPURequest), formatMilliCPU(u.CPULimit), formatRatio(u.cpuLimitRatio()),
+			formatBytes(u.MemUsage), formatBytes(u.MemRequest), formatBytes(u.MemLimit), formatRatio(u.memLimitRatio()),
+			u.status())
+	}
+	w.Flush()
+
+	// Repeat at-risk containers in color so they stand out from the table
+	for _, u := range usage {
+		if s := u.status(); s != "OK" {
+			fmt.Printf("%s%s: %s/%s%s\n", colorRed, s, u.Pod, u.Container, colorReset)
+		}
+	}
+}
+
+// formatMilliCPU renders millicores, using '-' for unset values
+func formatMilliCPU(milli int64) string {
+	if milli == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dm", milli)
+}
+
+// formatBytes renders bytes in Mi, using '-' for unset values
+func formatBytes(bytes int64) string {
+	if bytes == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
+
+// formatRatio renders a usage ratio as a percentage, using '-' when no limit is set
+func formatRatio(ratio float64) string {
+	if ratio == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
@@ -0,0 +1,156 @@
+package podshell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testPod returns a pod with one container per entry of limits, keyed by container
+// name with CPU and memory limits
+func testPod(name string, limits map[string][2]string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}}
+	for container, limit := range limits {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name: container,
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(limit[0]),
+				corev1.ResourceMemory: resource.MustParse(limit[1]),
+			}},
+		})
+	}
+	return pod
+}
+
+// testPodMetrics returns metrics for a pod, keyed by container name with CPU and memory usage
+func testPodMetrics(name string, usage map[string][2]string) metricsv1beta1.PodMetrics {
+	pm := metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}}
+	for container, u := range usage {
+		pm.Containers = append(pm.Containers, metricsv1beta1.ContainerMetrics{
+			Name: container,
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(u[0]),
+				corev1.ResourceMemory: resource.MustParse(u[1]),
+			},
+		})
+	}
+	return pm
+}
+
+func TestCollectUsage(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testPod("api", map[string][2]string{"app": {"500m", "256Mi"}}),
+		testPod("worker", map[string][2]string{"app": {"1", "1Gi"}}),
+		testPod("cache", map[string][2]string{"redis": {"1", "1Gi"}}),
+	)
+	// The fake tracker cannot map PodMetrics to its "pods" resource, so list
+	// through a reactor
+	metrics := metricsfake.NewSimpleClientset()
+	metrics.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{
+			testPodMetrics("api", map[string][2]string{"app": {"480m", "100Mi"}}),
+			testPodMetrics("worker", map[string][2]string{"app": {"200m", "950Mi"}}),
+			testPodMetrics("cache", map[string][2]string{"redis": {"100m", "100Mi"}}),
+			testPodMetrics("gone", map[string][2]string{"app": {"50m", "10Mi"}}),
+		}}, nil
+	})
+
+	usage, err := collectUsage(context.Background(), client, metrics, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		pod, status string
+		cpuLimit    int64
+	}{
+		{"api", "THROTTLING", 500},
+		{"worker", "NEAR OOM", 1000},
+		{"cache", "OK", 1000},
+		{"gone", "OK", 0}, // Deleted since sampled: no spec, no limits
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("got %d containers, want %d", len(usage), len(want))
+	}
+	for i, w := range want {
+		u := usage[i]
+		if u.Pod != w.pod || u.status() != w.status || u.CPULimit != w.cpuLimit {
+			t.Errorf("usage[%d] = %s %s (cpu limit %dm), want %s %s (cpu limit %dm)",
+				i, u.Pod, u.status(), u.CPULimit, w.pod, w.status, w.cpuLimit)
+		}
+	}
+}
+
+func TestTopPodsLeavesInputAfterCancel(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+		w.Close()
+	})
+
+	// Signal each refresh so the view can be cancelled mid-refresh
+	refreshed := make(chan struct{}, 10)
+	metrics := metricsfake.NewSimpleClientset()
+	metrics.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		refreshed <- struct{}{}
+		return true, &metricsv1beta1.PodMetricsList{}, nil
+	})
+	a := &AccessPods{client: fake.NewSimpleClientset(), metrics: metrics}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fmt.Fprintln(w, "1") // Refresh interval
+	result := make(chan error, 1)
+	go func() { result <- a.topPods(ctx, "shop") }()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-refreshed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("usage was not refreshed")
+		}
+	}
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("topPods did not return after cancellation")
+	}
+
+	// The next line, e.g. a menu choice, is left for the caller
+	fmt.Fprintln(w, "3")
+	read := make(chan int, 1)
+	go func() {
+		var choice int
+		fmt.Scan(&choice)
+		read <- choice
+	}()
+	select {
+	case choice := <-read:
+		if choice != 3 {
+			t.Errorf("read %d, want 3", choice)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the line after cancellation was consumed by topPods")
+	}
+}
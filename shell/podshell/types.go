@@ -1,40 +1,43 @@
 package podshell
 
-// CommandType 定義可用的命令類型
-type CommandType int
+import (
+	"time"
 
-const (
-	ShowPods CommandType = iota
-	ConnectPod
-	ShowLogs
-	DescribePod
-	ShowEnv
-	AdjustCPU
-	AdjustMemory
-	ScaleDeployment
-	PortForward
-	Exit
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 // ClusterConfig holds the configuration for a GKE cluster
 // format example:
-// env | project | cluster | zone | namespace
+// env | project | cluster | zone | namespace [| flags]
 // dev|project-dev|cluster-dev|us-central1-a|default
 // staging|project-stg|cluster-stg|us-central1-b|staging
-// prod|project-prod|cluster-prod|us-central1-c|production
+// prod|project-prod|cluster-prod|us-central1-c|production|protected
+// local||||default|context=kind-local
+// prod|project-prod|cluster-prod|us-central1-c|production|account=ops@example.com,impersonate=deployer@project-prod.iam.gserviceaccount.com
+// dev|project-dev|cluster-dev|us-central1-a|default|timeout=2m,retries=5
+// or the structured YAML format, see parseStructuredConfig
 type ClusterConfig struct {
-	env       string // Environment name (e.g., dev, staging, prod)
-	project   string // GCP project ID
-	cluster   string // GKE cluster name
-	zone      string // GCP zone where the cluster is located
-	namespace string // Kubernetes namespace
-}
+	env         string // Environment name (e.g., dev, staging, prod)
+	project     string // GCP project ID
+	cluster     string // GKE cluster name
+	zone        string // GCP zone where the cluster is located
+	namespace   string // Kubernetes namespace
+	protected   bool   // Confirms mutating actions and refuses hibernation; always set for prod, production and prd
+	kubeContext string // Kubeconfig context used directly instead of fetching GKE credentials
+
+	// Optional gcloud identity, applied to this environment's session only
+	account         string // gcloud account to use instead of the active one
+	impersonate     string // Service account to impersonate
+	credentialsFile string // Credentials file overriding the gcloud login
+
+	// Optional access path for private control planes
+	proxy  string // http, https or socks5 proxy URL for Kubernetes API traffic
+	tunnel string // Command serving the proxy, e.g. an IAP tunnel; split on spaces, not run by a shell
 
-// ShellCommand represents a single command with its action
-type ShellCommand struct {
-	Type        CommandType
-	Description string
-	Action      func(namespace string) error
+	timeout time.Duration // Limit for a single backend operation; defaults to AccessPods.Timeout
+	retries *int          // Retries of transient failures; nil uses AccessPods.Retries
+	verbose bool          // Report retries; set from AccessPods.Verbose
 }
 
 type DBConfig struct {
@@ -47,14 +50,30 @@ type DBConfig struct {
 
 // AccessPods is the main structure for handling pod access
 type AccessPods struct {
-	FilePath string // Path to the configuration file
-	Commands map[CommandType]ShellCommand
+	FilePath string        // Path to the configuration file
+	Env      string        // Environment the interactive shell connects to; prompted for when empty
+	Timeout  time.Duration // Default limit for a single backend operation; zero disables it
+	Retries  int           // Default retries of transient failures; zero disables them
+	Verbose  bool          // Report retries on stderr
+	Commands *Registry     // Actions offered in the interactive menu
+
+	config  ClusterConfig           // Configuration of the connected cluster
+	client  kubernetes.Interface    // Kubernetes API client for the connected cluster
+	metrics metricsclient.Interface // metrics.k8s.io client for usage views
+	tunnel  *tunnel                 // Supervised tunnel process of the session, if any
+
+	unavailable          map[string][]Permission // Actions the user lacks permissions for, by name
+	permissionsNamespace string                  // Namespace unavailable was computed for
 }
 
+// defaultTimeout bounds a single backend call unless configured otherwise
+const defaultTimeout = 60 * time.Second
+
 // ANSI color codes for terminal output formatting
 const (
 	colorRed    = "\033[0;31m"
 	colorGreen  = "\033[0;32m"
 	colorYellow = "\033[1;33m"
+	colorGrey   = "\033[0;90m"
 	colorReset  = "\033[0m"
 )
@@ -2,27 +2,201 @@ package podshell
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+// connectToCluster prepares kubectl access to the environment's cluster. A
+// kubeconfig context is only checked to exist, as kubectl commands select it
+// with --context rather than switching the user's current context; GKE
+// clusters have their credentials fetched.
+func connectToCluster(ctx context.Context, config ClusterConfig) error {
+	if config.kubeContext != "" {
+		if _, err := runKubectl(ctx, config, nil, "config", "get-contexts", config.kubeContext); err != nil {
+			return fmt.Errorf("failed to find context %s: %w", config.kubeContext, err)
+		}
+		return nil
+	}
+	return connectToGKE(ctx, config)
+}
+
 // connectToGKE establishes connection to a GKE cluster using gcloud command
 // Command: gcloud container clusters get-credentials my-cluster --zone us-central1-a --project my-project
-func connectToGKE(config ClusterConfig) error {
-	// Constructs and executes gcloud command to get cluster credentials
-	cmd := exec.Command("gcloud", "container", "clusters", "get-credentials",
-		config.cluster, "--zone", config.zone, "--project", config.project)
-	return cmd.Run()
+func connectToGKE(ctx context.Context, config ClusterConfig) error {
+	return fetchCredentials(ctx, config, "")
+}
+
+// fetchCredentials runs gcloud container clusters get-credentials, writing to
+// kubeconfig or, when empty, the default kubeconfig. Transient failures such
+// as token refresh races are retried.
+func fetchCredentials(ctx context.Context, config ClusterConfig, kubeconfig string) error {
+	return config.retryPolicy().do(ctx, "get-credentials", func() error {
+		opCtx, cancel := withTimeout(ctx, config.timeout)
+		defer cancel()
+
+		// Constructs and executes gcloud command to get cluster credentials
+		cmd := gcloudCommand(opCtx, config, "container", "clusters", "get-credentials",
+			config.cluster, "--zone", config.zone, "--project", config.project)
+		if kubeconfig != "" {
+			cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to get credentials for %s: %w", config.cluster, operationError(opCtx, config.timeout, commandError(err, out)))
+		}
+		return nil
+	})
+}
+
+// loadRestConfig loads the REST client configuration from a kubeconfig file.
+// An empty path uses the default loading rules ($KUBECONFIG or ~/.kube/config),
+// which is where connectToGKE writes the cluster credentials. An empty context
+// uses the current context.
+func loadRestConfig(kubeconfig, context string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	return restConfig, nil
+}
+
+// connectToEnvironment fetches credentials for another cluster into a temporary
+// kubeconfig, leaving the current kubectl context untouched.
+// The returned cleanup function removes the temporary kubeconfig.
+// Environments with a kubeconfig context use that context directly, and a
+// configured tunnel is started first and stopped by cleanup.
+func connectToEnvironment(ctx context.Context, config ClusterConfig) (kubernetes.Interface, func(), error) {
+	cleanup := func() {}
+	if config.tunnel != "" {
+		t, err := startTunnel(ctx, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = t.close
+	}
+
+	if config.kubeContext != "" {
+		client, err := newEnvironmentClient("", config)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		return client, cleanup, nil
+	}
+
+	file, err := os.CreateTemp("", "go-gcp-kubeconfig-*")
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to create temporary kubeconfig: %v", err)
+	}
+	file.Close()
+	closeTunnel := cleanup
+	cleanup = func() {
+		os.Remove(file.Name())
+		closeTunnel()
+	}
+
+	if err := fetchCredentials(ctx, config, file.Name()); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("%s: %w", config.env, err)
+	}
+
+	client, err := newEnvironmentClient(file.Name(), config)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return client, cleanup, nil
+}
+
+// newEnvironmentClient builds an API client that authenticates as the environment's identity
+func newEnvironmentClient(kubeconfig string, config ClusterConfig) (kubernetes.Interface, error) {
+	restConfig, err := loadRestConfig(kubeconfig, config.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	applyIdentity(restConfig, config)
+	if err := applyProxy(restConfig, config); err != nil {
+		return nil, err
+	}
+	applyRetry(restConfig, config)
+	restConfig.Timeout = config.timeout
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// withTimeout bounds a single backend operation; a zero timeout only inherits
+// the cancellation of ctx
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// operationError explains a failure caused by the operation's context ending,
+// which otherwise surfaces as "signal: killed"
+func operationError(ctx context.Context, timeout time.Duration, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &Error{Kind: KindTimeout, Err: fmt.Errorf("timed out after %v", timeout)}
+	case errors.Is(ctx.Err(), context.Canceled):
+		return ctx.Err()
+	}
+	return err
+}
+
+// runKubectl runs a non-interactive kubectl command for the environment, bounded by its operation
+// timeout. With out nil the output is captured and returned; otherwise it is
+// streamed to out. Errors include what kubectl printed to stderr.
+func runKubectl(ctx context.Context, config ClusterConfig, out io.Writer, args ...string) ([]byte, error) {
+	timeout := config.timeout
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := kubectlCommand(ctx, config, args...)
+	var output []byte
+	var err error
+	if out != nil {
+		cmd.Stdout = out
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		err = cmd.Run()
+	} else {
+		cmd.Stderr = &stderr
+		output, err = cmd.Output()
+	}
+	if err != nil {
+		return nil, operationError(ctx, timeout, commandError(err, stderr.Bytes()))
+	}
+	return output, nil
 }
 
 // getPods retrieves the list of pods in the specified namespace
 // Command: kubectl get pods -n bi-rpa-pd-pnc --no-headers
-func getPods(namespace string) ([]string, error) {
-	// Execute kubectl command to get pod list
-	cmd := exec.Command("kubectl", "get", "pods", "-n", namespace, "--no-headers")
-	output, err := cmd.Output()
+func getPods(ctx context.Context, config ClusterConfig, namespace string) ([]string, error) {
+	// Execute kubectl command to get pod list, retrying transient failures
+	var output []byte
+	err := config.retryPolicy().do(ctx, "list pods", func() error {
+		var err error
+		output, err = runKubectl(ctx, config, nil, "get", "pods", "-n", namespace, "--no-headers")
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -55,16 +229,70 @@ func selectPod(pods []string) (string, error) {
 
 	// Validate user selection
 	if choice < 1 || choice > len(pods) {
-		return "", fmt.Errorf("invalid pod selection")
+		return "", invalidInput("invalid pod selection")
 	}
 	return pods[choice-1], nil
 }
 
+// selectPodObject lists pods through the API, lets the user pick one and
+// returns the full pod object for callers that need its spec or status.
+func selectPodObject(ctx context.Context, client kubernetes.Interface, namespace string) (*corev1.Pod, error) {
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(podList.Items) == 0 {
+		return nil, fmt.Errorf("no pods found in namespace %s", namespace)
+	}
+	names := make([]string, 0, len(podList.Items))
+	for _, p := range podList.Items {
+		names = append(names, p.Name)
+	}
+
+	selectedPod, err := selectPod(names)
+	if err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		if podList.Items[i].Name == selectedPod {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("pod %s not found", selectedPod)
+}
+
+// selectContainer lets the user pick a container when the pod has more than one.
+// Single-container pods are selected without prompting.
+func selectContainer(pod *corev1.Pod) (string, error) {
+	containers := pod.Spec.Containers
+	if len(containers) == 0 {
+		return "", fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+	if len(containers) == 1 {
+		return containers[0].Name, nil
+	}
+
+	fmt.Printf("\n%sAvailable containers:%s\n", colorYellow, colorReset)
+	for i, c := range containers {
+		fmt.Printf("%d. %s\n", i+1, c.Name)
+	}
+
+	var choice int
+	fmt.Printf("\nSelect container (1-%d): ", len(containers))
+	fmt.Scan(&choice)
+
+	if choice < 1 || choice > len(containers) {
+		return "", invalidInput("invalid container selection")
+	}
+	return containers[choice-1].Name, nil
+}
+
 // connectToPod establishes an interactive shell connection to the selected pod
 // Command: kubectl exec -it pod-name -n namespace -- /bin/sh
-func connectToPod(pod, namespace string) error {
+// The session is not bounded by the operation timeout, only cancelled with ctx.
+func connectToPod(ctx context.Context, config ClusterConfig, pod, namespace string) error {
 	// Setup interactive shell connection to the pod
-	cmd := exec.Command("kubectl", "exec", "-it", pod, "-n", namespace, "--", "/bin/sh")
+	cmd := kubectlCommand(ctx, config, "exec", "-it", pod, "-n", namespace, "--", "/bin/sh")
 
 	// Connect standard input/output/error for interactive session
 	cmd.Stdin = os.Stdin
@@ -73,57 +301,12 @@ func connectToPod(pod, namespace string) error {
 	return cmd.Run()
 }
 
-// readConfigurations reads and parses the cluster configuration file
-// File format: env|project|cluster|zone|namespace
-// Example line: prod|my-project|my-cluster|us-central1-a|default
-func readConfigurations(filePath string) ([]ClusterConfig, error) {
-	// Open and defer close of configuration file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open configuration file: %v", err)
-	}
-	defer file.Close()
-
-	var configs []ClusterConfig
-	scanner := bufio.NewScanner(file)
-
-	// Parse configuration file line by line
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Split and validate configuration line
-		parts := strings.Split(line, "|")
-		if len(parts) != 5 {
-			return nil, fmt.Errorf("invalid configuration line format: %s", line)
-		}
-
-		// Create and validate config object
-		config := ClusterConfig{
-			env:       strings.TrimSpace(parts[0]),
-			project:   strings.TrimSpace(parts[1]),
-			cluster:   strings.TrimSpace(parts[2]),
-			zone:      strings.TrimSpace(parts[3]),
-			namespace: strings.TrimSpace(parts[4]),
-		}
-
-		// Verify all required fields are present
-		if config.env == "" || config.project == "" || config.cluster == "" ||
-			config.zone == "" || config.namespace == "" {
-			return nil, fmt.Errorf("missing required fields in line: %s", line)
-		}
-		configs = append(configs, config)
-	}
-
-	// Check for scanner errors and valid configurations
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading configuration file: %v", err)
-	}
-	if len(configs) == 0 {
-		return nil, fmt.Errorf("no valid configurations found")
+// isProductionEnv reports whether an environment name denotes production:
+// prod, production or prd, in any case
+func isProductionEnv(env string) bool {
+	switch strings.ToLower(env) {
+	case "prod", "production", "prd":
+		return true
 	}
-	return configs, nil
+	return false
 }